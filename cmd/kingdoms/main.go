@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	_ "time/tzdata" // time zones requested by clients must resolve without system tzdata
 
 	"kingdoms/internal/server/app"
 )
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"kingdoms/internal/database/connect"
 	"kingdoms/internal/database/schema"
+	"kingdoms/internal/database/slug"
+	"kingdoms/internal/server/app/appState"
 
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
@@ -24,6 +26,30 @@ func main() {
 		fmt.Println(err)
 		return
 	}
+
+	err = FillKingdomSlugs(db)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	err = MigrateApplicationStates(db)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	err = CreateSingleDraftIndex(db)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	err = MigrateTrash(db)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 }
 
 func MigrateSchema(db *gorm.DB) error {
@@ -47,5 +73,147 @@ func MigrateSchema(db *gorm.DB) error {
 		return err
 	}
 
+	err = db.AutoMigrate(&schema.KingdomTranslation{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&schema.KingdomSlug{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&schema.ApplicationEvent{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&schema.ApplicationComment{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&schema.ApplicationSnapshot{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&schema.PurgeLog{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&schema.Notification{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&schema.Attachment{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&schema.Charter{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&schema.ApplicationApproval{})
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&schema.ApplicationCoAuthor{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FillKingdomSlugs gives a slug to every kingdom created before slugs existed.
+func FillKingdomSlugs(db *gorm.DB) error {
+	taken := make(map[string]bool)
+
+	var existing []string
+	err := db.Model(&schema.Kingdom{}).Where("slug IS NOT NULL AND slug != ''").Pluck("slug", &existing).Error
+	if err != nil {
+		return err
+	}
+
+	var former []string
+	err = db.Model(&schema.KingdomSlug{}).Pluck("slug", &former).Error
+	if err != nil {
+		return err
+	}
+
+	for _, s := range append(existing, former...) {
+		taken[s] = true
+	}
+
+	var kingdoms []schema.Kingdom
+	err = db.Select("id, name").Where("slug IS NULL OR slug = ''").Order("id").Find(&kingdoms).Error
+	if err != nil {
+		return err
+	}
+
+	for _, kingdom := range kingdoms {
+		kingdomSlug, _ := slug.Unique(slug.Make(kingdom.Name), func(candidate string) (bool, error) {
+			return taken[candidate], nil
+		})
+		taken[kingdomSlug] = true
+
+		err = db.Model(&schema.Kingdom{}).Where("id = ?", kingdom.Id).Update("slug", kingdomSlug).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MigrateApplicationStates replaces the Russian labels applications used to
+// be stored with by state codes.
+func MigrateApplicationStates(db *gorm.DB) error {
+	for _, state := range appState.All {
+		err := db.Model(&schema.RulerApplication{}).
+			Where("state IN ?", appState.LegacyLabels(state)).
+			Update("state", state).Error
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// CreateSingleDraftIndex guarantees at most one draft per user. Users who
+// already have several keep the latest one; the others are marked deleted.
+func CreateSingleDraftIndex(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		latest := tx.Model(&schema.RulerApplication{}).
+			Select("MAX(id)").
+			Where("state = ?", appState.Draft).
+			Group("creator_refer")
+
+		err := tx.Model(&schema.RulerApplication{}).
+			Where("state = ? AND id NOT IN (?)", appState.Draft, latest).
+			Update("state", appState.Deleted).Error
+		if err != nil {
+			return err
+		}
+
+		// DDL takes no bind parameters, hence the inlined state code
+		return tx.Exec(fmt.Sprintf(`CREATE UNIQUE INDEX IF NOT EXISTS idx_ruler_applications_single_draft
+			ON ruler_applications (creator_refer) WHERE state = '%s'`, appState.Draft)).Error
+	})
+}
+
+// MigrateTrash dates applications deleted before the trash existed as deleted
+// now, so they get the full retention before the purge removes them. Their
+// previous state is unknown and left empty.
+func MigrateTrash(db *gorm.DB) error {
+	return db.Model(&schema.RulerApplication{}).
+		Where("state = ? AND date_delete IS NULL", appState.Deleted).
+		Update("date_delete", gorm.Expr("now()")).Error
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"kingdoms/internal/config"
+	"kingdoms/internal/database/connect"
+	"kingdoms/internal/server/app/locale"
+	"kingdoms/internal/server/processing"
+	"kingdoms/internal/snapshot"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	out := flag.String("out", "snapshot", "directory to write the static API into")
+	pageSize := flag.Int("page-size", 50, "kingdoms per list page")
+	locales := flag.String("locales", "ru,en,uk", "comma separated locales to write")
+	flag.Parse()
+
+	_ = godotenv.Load()
+	repo, err := processing.New(connect.FromEnv(), &config.Config{})
+	if err != nil {
+		fmt.Println("Failed to connect database! Error:", err)
+		return
+	}
+
+	opts := snapshot.Options{
+		Out:      *out,
+		PageSize: *pageSize,
+	}
+
+	for _, str := range strings.Split(*locales, ",") {
+		loc, ok := locale.Parse(str)
+		if !ok {
+			fmt.Println("Unsupported locale:", str)
+			return
+		}
+
+		opts.Locales = append(opts.Locales, loc)
+	}
+
+	result, err := snapshot.Generate(repo, opts)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("Snapshot written to %s: %d files, %d changed, %d removed\n",
+		*out, result.Files, result.Written, result.Removed)
+}
@@ -0,0 +1,123 @@
+// Package charter renders the printable charter (грамота) granted with an
+// approved application.
+package charter
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"time"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	fontFamily = "charter"
+	dateLayout = "02.01.2006"
+	qrSize     = 40 // mm
+	qrPixels   = 512
+)
+
+type Kingdom struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+// Charter is what the document says.
+type Charter struct {
+	Ruler        string
+	Kingdoms     []Kingdom
+	Moderator    string
+	DecisionDate time.Time
+	Code         string
+	VerifyURL    string
+}
+
+// Render lays the charter out on an A4 page. fontPath is a TrueType font with
+// Cyrillic glyphs, e.g. DejaVuSans.ttf.
+func Render(charter Charter, fontPath string) ([]byte, error) {
+	font, err := os.ReadFile(fontPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading charter font: %w", err)
+	}
+
+	code, err := qr.Encode(charter.VerifyURL, qr.M, qr.Auto)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err = barcode.Scale(code, qrPixels, qrPixels)
+	if err != nil {
+		return nil, err
+	}
+
+	// the scaled code is 16-bit gray, which the PDF writer cannot embed
+	gray := image.NewGray(code.Bounds())
+	draw.Draw(gray, gray.Bounds(), code, code.Bounds().Min, draw.Src)
+
+	var qrImage bytes.Buffer
+	err = png.Encode(&qrImage, gray)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Грамота: "+charter.Ruler, true)
+	pdf.SetCreationDate(charter.DecisionDate)
+	pdf.AddUTF8FontFromBytes(fontFamily, "", font)
+	pdf.SetMargins(25, 25, 25)
+	pdf.AddPage()
+
+	width, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	textWidth := width - left - right
+
+	pdf.SetFont(fontFamily, "", 32)
+	pdf.CellFormat(textWidth, 20, "Грамота", "", 1, "C", false, 0, "")
+	pdf.Ln(8)
+
+	pdf.SetFont(fontFamily, "", 13)
+	pdf.MultiCell(textWidth, 7, "Настоящей грамотой удостоверяется, что "+charter.Ruler+
+		" признаётся правителем следующих королевств на указанные сроки:", "", "L", false)
+	pdf.Ln(6)
+
+	nameWidth := textWidth - 2*35
+	pdf.SetFont(fontFamily, "", 12)
+	pdf.CellFormat(nameWidth, 8, "Королевство", "B", 0, "L", false, 0, "")
+	pdf.CellFormat(35, 8, "С", "B", 0, "C", false, 0, "")
+	pdf.CellFormat(35, 8, "По", "B", 1, "C", false, 0, "")
+	for _, kingdom := range charter.Kingdoms {
+		pdf.CellFormat(nameWidth, 8, kingdom.Name, "", 0, "L", false, 0, "")
+		pdf.CellFormat(35, 8, kingdom.From.Format(dateLayout), "", 0, "C", false, 0, "")
+		pdf.CellFormat(35, 8, kingdom.To.Format(dateLayout), "", 1, "C", false, 0, "")
+	}
+	pdf.Ln(12)
+
+	pdf.SetFont(fontFamily, "", 13)
+	pdf.CellFormat(textWidth, 8, "Утвердил: "+charter.Moderator, "", 1, "L", false, 0, "")
+	pdf.CellFormat(textWidth, 8, "Дата решения: "+charter.DecisionDate.Format(dateLayout), "", 1, "L", false, 0, "")
+	pdf.Ln(10)
+
+	y := pdf.GetY()
+	pdf.RegisterImageOptionsReader("qr", fpdf.ImageOptions{ImageType: "PNG"}, &qrImage)
+	pdf.ImageOptions("qr", left, y, qrSize, qrSize, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, charter.VerifyURL)
+
+	pdf.SetFont(fontFamily, "", 10)
+	pdf.SetXY(left+qrSize+5, y+qrSize/2-8)
+	pdf.MultiCell(textWidth-qrSize-5, 5, "Подлинность грамоты можно проверить по QR-коду или по адресу\n"+
+		charter.VerifyURL+"\nКод грамоты: "+charter.Code, "", "L", false)
+
+	var out bytes.Buffer
+	err = pdf.Output(&out)
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
@@ -17,8 +17,21 @@ type Config struct {
 	ServiceHost string
 	ServicePort int
 
-	JWT   JWTConfig
-	Redis RedisConfig
+	JWT         JWTConfig
+	Redis       RedisConfig
+	Comments    CommentsConfig
+	Claims      ClaimsConfig
+	Queue       QueueConfig
+	Trash       TrashConfig
+	Drafts      DraftsConfig
+	Attachments AttachmentsConfig
+	Charter     CharterConfig
+	Approvals   ApprovalsConfig
+}
+
+type CommentsConfig struct {
+	// EditWindow is how long after posting an author may edit or delete a comment.
+	EditWindow time.Duration
 }
 
 type RedisConfig struct {
@@ -30,6 +43,80 @@ type RedisConfig struct {
 	ReadTimeout time.Duration
 }
 
+type ClaimsConfig struct {
+	// BlockApprovedOverlaps refuses kingdom periods that overlap a period
+	// already granted by an approved application.
+	BlockApprovedOverlaps bool
+}
+
+type QueueConfig struct {
+	// LockTTL is how long a moderator keeps an application taken from the
+	// queue before it returns to the queue for others.
+	LockTTL time.Duration
+}
+
+type TrashConfig struct {
+	// RestoreWindow is how long after deletion an application can be restored.
+	RestoreWindow time.Duration
+	// Retention is how long deleted applications are kept before the purge
+	// removes them for good.
+	Retention time.Duration
+	// PurgeInterval is how often the purge runs; zero disables it.
+	PurgeInterval time.Duration
+}
+
+type DraftsConfig struct {
+	// ExpireAfterDays is how many days a draft may stay untouched before it
+	// expires into the trash; zero keeps drafts forever.
+	ExpireAfterDays int
+	// WarnBeforeDays is how many days before expiry the owner is warned. A
+	// draft never expires sooner than this after the warning.
+	WarnBeforeDays int
+	// CheckInterval is how often drafts are checked for expiry.
+	CheckInterval time.Duration
+}
+
+type AttachmentsConfig struct {
+	// Dir is where the local storage keeps uploaded files.
+	Dir string
+	// MaxSize is the largest file accepted, in bytes.
+	MaxSize int64
+	// AllowedTypes lists the MIME types accepted, detected from the content
+	// rather than trusted from the client.
+	AllowedTypes []string
+}
+
+type CharterConfig struct {
+	// FontPath is a TrueType font with Cyrillic glyphs the charter is set in.
+	FontPath string
+	// PublicURL is where the server is reached from outside; the charter QR
+	// code links to its verification page there.
+	PublicURL string
+}
+
+type ApprovalsConfig struct {
+	// Chains are tried in order and the first one matching the application
+	// applies. Without a match a single moderator approval is enough.
+	Chains []ApprovalChainConfig
+}
+
+type ApprovalChainConfig struct {
+	Name string
+	// MinKingdoms and MinArea select the applications the chain is for: those
+	// with at least that many kingdoms or at least that total area. A chain
+	// with neither matches every application.
+	MinKingdoms int
+	MinArea     int
+	// Stages must be signed off in order, each by a different moderator.
+	Stages []ApprovalStageConfig
+}
+
+type ApprovalStageConfig struct {
+	Name string
+	// Role is the lowest role that may sign the stage: Manager or Admin.
+	Role string
+}
+
 type JWTConfig struct {
 	ExpiresIn     time.Duration
 	SigningMethod jwt.SigningMethod
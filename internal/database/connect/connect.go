@@ -16,5 +16,5 @@ func FromEnv() string {
 	pass := os.Getenv("DB_PASS")
 	dbname := os.Getenv("DB_NAME")
 
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, pass, dbname)
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=UTC", host, port, user, pass, dbname)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"kingdoms/internal/database/connect"
 	"kingdoms/internal/database/schema"
+	"kingdoms/internal/database/slug"
 	"log"
 	"math/rand"
 	"os"
@@ -36,6 +37,7 @@ func main() {
 
 	var kingdoms []schema.Kingdom
 	kingdomNames := make(map[string]bool)
+	kingdomSlugs := make(map[string]bool)
 
 	for i := 0; i < 200; i++ {
 		kingdomName, kingdomCapital := getKingdomNameAndCapital()
@@ -47,6 +49,11 @@ func main() {
 		kingdomNames[kingdomName] = true
 		kingdomArea := rand.Intn(100000)
 
+		kingdomSlug, _ := slug.Unique(slug.Make(kingdomName), func(candidate string) (bool, error) {
+			return kingdomSlugs[candidate], nil
+		})
+		kingdomSlugs[kingdomSlug] = true
+
 		kingdom := schema.Kingdom{
 			Name:        kingdomName,
 			Area:        kingdomArea,
@@ -54,6 +61,7 @@ func main() {
 			Image:       defaultAvatar,
 			Description: getKingdomDescription(kingdomName, kingdomCapital, strconv.Itoa(kingdomArea)),
 			State:       getKingdomState(),
+			Slug:        kingdomSlug,
 		}
 		kingdoms = append(kingdoms, kingdom)
 	}
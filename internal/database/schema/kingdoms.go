@@ -1,21 +1,26 @@
 package schema
 
 import (
+	"kingdoms/internal/server/app/appState"
 	role "kingdoms/internal/server/app/userRole"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 type Kingdom struct {
-	Id          uint   `gorm:"primaryKey;AUTO_INCREMENT"`
-	Name        string `gorm:"type:varchar(100);unique;not null"`
-	Area        int    `gorm:"not null"`
-	Capital     string `gorm:"type:varchar(50);not null"`
-	Image       string `gorm:"type:bytea"`
-	Description string `gorm:"size:255"`
-	State       string `gorm:"type:varchar(50);not null"`
+	Id          uint     `gorm:"primaryKey;AUTO_INCREMENT"`
+	Name        string   `gorm:"type:varchar(100);unique;not null"`
+	Area        int      `gorm:"not null"`
+	Capital     string   `gorm:"type:varchar(50);not null"`
+	Image       string   `gorm:"type:bytea"`
+	Description string   `gorm:"size:255"`
+	State       string   `gorm:"type:varchar(50);not null"`
+	Slug        string   `gorm:"type:varchar(150);uniqueIndex"`
+	Latitude    *float64 // capital coordinates, unknown for most kingdoms
+	Longitude   *float64
 }
 
 type User struct {
@@ -24,20 +29,26 @@ type User struct {
 	Name     string    `json:"Name"`
 	Role     role.Role `sql:"type:string"`
 	Password string
+	TimeZone string `gorm:"type:varchar(64)"` // IANA zone for rendering dates, e.g. Europe/Kaliningrad
 }
 
 type RulerApplication struct {
-	Id             uint      `gorm:"primaryKey;AUTO_INCREMENT"`
-	State          string    `gorm:"type:varchar(50);not null"`
-	DateCreate     time.Time `gorm:"not null;default:now()"`
+	Id             uint           `gorm:"primaryKey;AUTO_INCREMENT"`
+	State          appState.State `gorm:"type:varchar(50);not null"`
+	DateCreate     time.Time      `gorm:"not null;default:now()"`
 	DateSend       time.Time
 	DateComplete   time.Time
 	Ruler          string `gorm:"type:varchar(50);not null"`
 	CreatorRefer   int    `gorm:"not null"`
 	Creator        User   `gorm:"foreignKey:CreatorRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	ModeratorRefer *int
-	Moderator      User `gorm:"foreignKey:ModeratorRefer"`
-	Check          bool `gorm:"type:boolean"`
+	Moderator      User           `gorm:"foreignKey:ModeratorRefer"`
+	Check          bool           `gorm:"type:boolean"`
+	PreviousState  appState.State `gorm:"type:varchar(50)"` // state before the application went to the trash
+	DateDelete     *time.Time
+	LastModified   time.Time             `gorm:"not null;default:now()"` // last change of the ruler or kingdom lines
+	DateWarned     *time.Time            // when the owner was warned that the draft is about to expire
+	CoAuthors      []ApplicationCoAuthor `gorm:"foreignKey:ApplicationRefer" json:",omitempty"`
 }
 
 type Kingdom2Application struct {
@@ -48,4 +59,159 @@ type Kingdom2Application struct {
 	Application      RulerApplication `gorm:"foreignKey:ApplicationRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	From             datatypes.Date   `gorm:"not null"`
 	To               datatypes.Date   `gorm:"not null"`
+	Justification    string           `gorm:"type:text"`
+	Sources          datatypes.JSON   // cited sources, a JSON list of strings
+	Decision         string           `gorm:"type:varchar(20);not null;default:'pending'"` // moderator verdict on this line
+	DecisionComment  string           `gorm:"type:text"`
+	DeciderRefer     *int
+}
+
+type KingdomTranslation struct {
+	Id           uint    `gorm:"primaryKey;AUTO_INCREMENT"`
+	KingdomRefer int     `gorm:"not null;uniqueIndex:idx_kingdom_translation_locale"`
+	Kingdom      Kingdom `gorm:"foreignKey:KingdomRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Locale       string  `gorm:"type:varchar(5);not null;uniqueIndex:idx_kingdom_translation_locale"`
+	Name         string  `gorm:"type:varchar(100)"`
+	Capital      string  `gorm:"type:varchar(50)"`
+	Description  string  `gorm:"size:255"`
+}
+
+// KingdomSlug keeps slugs a kingdom had before it was renamed so that old
+// links keep resolving.
+type KingdomSlug struct {
+	Id           uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	KingdomRefer int       `gorm:"not null"`
+	Kingdom      Kingdom   `gorm:"foreignKey:KingdomRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Slug         string    `gorm:"type:varchar(150);uniqueIndex;not null"`
+	DateCreate   time.Time `gorm:"not null;default:now()"`
+}
+
+type ApplicationEvent struct {
+	Id               uint             `gorm:"primaryKey;AUTO_INCREMENT"`
+	ApplicationRefer int              `gorm:"not null;index"`
+	Application      RulerApplication `gorm:"foreignKey:ApplicationRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	ActorRefer       int              `gorm:"not null"`
+	Actor            User             `gorm:"foreignKey:ActorRefer"`
+	Type             string           `gorm:"type:varchar(50);not null"`
+	KingdomRefer     *int
+	OldValue         datatypes.JSON
+	NewValue         datatypes.JSON
+	Comment          string    `gorm:"type:text"`
+	DateCreate       time.Time `gorm:"not null;default:now()"`
+}
+
+type ApplicationComment struct {
+	Id               uint                 `gorm:"primaryKey;AUTO_INCREMENT"`
+	ApplicationRefer int                  `gorm:"not null;index"`
+	Application      RulerApplication     `gorm:"foreignKey:ApplicationRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	AuthorRefer      int                  `gorm:"not null"`
+	Author           User                 `gorm:"foreignKey:AuthorRefer"`
+	EventRefer       *int                 // state change the comment explains
+	Event            *ApplicationEvent    `gorm:"foreignKey:EventRefer;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	LineRefer        *int                 // kingdom line the comment is about
+	Line             *Kingdom2Application `gorm:"foreignKey:LineRefer;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	Text             string               `gorm:"type:text;not null"`
+	DateCreate       time.Time            `gorm:"not null;default:now()"`
+	DateUpdate       *time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// ApplicationSnapshot freezes what an application looked like when it left
+// draft, so moderators review what was submitted rather than live data.
+type ApplicationSnapshot struct {
+	Id               uint             `gorm:"primaryKey;AUTO_INCREMENT"`
+	ApplicationRefer int              `gorm:"not null;index"`
+	Application      RulerApplication `gorm:"foreignKey:ApplicationRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Data             datatypes.JSON   `gorm:"not null"`
+	DateCreate       time.Time        `gorm:"not null;default:now()"`
+}
+
+// PurgeLog records applications the trash purge deleted for good.
+type PurgeLog struct {
+	Id               uint           `gorm:"primaryKey;AUTO_INCREMENT"`
+	ApplicationRefer int            `gorm:"not null;index"` // no foreign key: the application is gone
+	CreatorRefer     int            `gorm:"not null"`
+	Ruler            string         `gorm:"type:varchar(50)"`
+	PreviousState    appState.State `gorm:"type:varchar(50)"`
+	DateDelete       time.Time      `gorm:"not null"`
+	DatePurge        time.Time      `gorm:"not null;default:now()"`
+}
+
+type Notification struct {
+	Id               uint      `gorm:"primaryKey;AUTO_INCREMENT"`
+	UserRefer        int       `gorm:"not null;index"`
+	User             User      `gorm:"foreignKey:UserRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Type             string    `gorm:"type:varchar(50);not null"`
+	ApplicationRefer *int      // no foreign key: the notification outlives a purged application
+	Text             string    `gorm:"type:text;not null"`
+	DateCreate       time.Time `gorm:"not null;default:now()"`
+}
+
+// Attachment is a file uploaded to an application, optionally about one of its
+// kingdom lines. The content lives in the file storage under StorageKey.
+type Attachment struct {
+	Id               uint                 `gorm:"primaryKey;AUTO_INCREMENT"`
+	ApplicationRefer int                  `gorm:"not null;index"`
+	Application      RulerApplication     `gorm:"foreignKey:ApplicationRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	LineRefer        *int                 // kingdom line the file supports
+	Line             *Kingdom2Application `gorm:"foreignKey:LineRefer;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	UploaderRefer    int                  `gorm:"not null"`
+	Uploader         User                 `gorm:"foreignKey:UploaderRefer" json:"-"`
+	Name             string               `gorm:"type:varchar(255);not null"`
+	MimeType         string               `gorm:"type:varchar(100);not null"`
+	Size             int64                `gorm:"not null"`
+	Checksum         string               `gorm:"type:char(64);not null"` // hex SHA-256 of the content
+	StorageKey       string               `gorm:"type:varchar(255);not null;unique" json:"-"`
+	DateCreate       time.Time            `gorm:"not null;default:now()"`
+}
+
+// Charter is the document granted with an approved application. Its code is
+// what the public verification page looks it up by; the rendered PDF is kept
+// in the file storage under StorageKey once generated.
+type Charter struct {
+	Id               uint             `gorm:"primaryKey;AUTO_INCREMENT"`
+	ApplicationRefer int              `gorm:"not null;index"`
+	Application      RulerApplication `gorm:"foreignKey:ApplicationRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Code             string           `gorm:"type:varchar(32);not null;unique"`
+	Content          datatypes.JSON   `json:"-"` // what the charter says, frozen when it is issued
+	StorageKey       string           `gorm:"type:varchar(255)" json:"-"`
+	DateCreate       time.Time        `gorm:"not null;default:now()"`
+	DateRevoked      *time.Time       // set when the application is reopened
+}
+
+// ApplicationApproval is one stage of the approval chain signed off by a
+// moderator. Signatures are revoked when the application is rejected or
+// reopened, and the chain starts over.
+type ApplicationApproval struct {
+	Id               uint             `gorm:"primaryKey;AUTO_INCREMENT"`
+	ApplicationRefer int              `gorm:"not null;index"`
+	Application      RulerApplication `gorm:"foreignKey:ApplicationRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Stage            int              `gorm:"not null"` // position in the chain, from 0
+	StageName        string           `gorm:"type:varchar(50);not null"`
+	ApproverRefer    int              `gorm:"not null"`
+	Approver         User             `gorm:"foreignKey:ApproverRefer"`
+	Comment          string           `gorm:"type:text"`
+	DateCreate       time.Time        `gorm:"not null;default:now()"`
+	DateRevoked      *time.Time
+}
+
+const (
+	CoAuthorPending  = "pending"
+	CoAuthorAccepted = "accepted"
+	CoAuthorDeclined = "declined"
+)
+
+// ApplicationCoAuthor is an invitation to edit an application with its owner.
+// The invited user becomes a co-author once they accept it.
+type ApplicationCoAuthor struct {
+	Id               uint             `gorm:"primaryKey;AUTO_INCREMENT"`
+	ApplicationRefer int              `gorm:"not null;uniqueIndex:idx_application_co_author"`
+	Application      RulerApplication `gorm:"foreignKey:ApplicationRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	UserRefer        int              `gorm:"not null;uniqueIndex:idx_application_co_author"`
+	User             User             `gorm:"foreignKey:UserRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	InviterRefer     int              `gorm:"not null"`
+	Inviter          User             `gorm:"foreignKey:InviterRefer"`
+	State            string           `gorm:"type:varchar(20);not null"`
+	DateCreate       time.Time        `gorm:"not null;default:now()"`
+	DateAnswer       *time.Time
 }
@@ -0,0 +1,90 @@
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const maxLength = 140
+
+// BGN/PCGN romanization, with the Ukrainian letters that are missing from the
+// Russian table. Hard and soft signs are dropped, as is usual for URLs.
+var bgn = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+// reserved are slugs a kingdom cannot take: the literal paths next to
+// kingdoms/:slug, which would shadow it, and the name of the feed's index
+// file in the static snapshot.
+var reserved = map[string]bool{
+	"export":       true,
+	"import":       true,
+	"translations": true,
+	"index":        true,
+}
+
+// Make transliterates name into a lowercase latin slug, for example
+// "Черниговское княжество" becomes "chernigovskoe-knyazhestvo".
+func Make(name string) string {
+	var builder strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(name) {
+		if latin, ok := bgn[r]; ok {
+			builder.WriteString(latin)
+			dash = false
+			continue
+		}
+
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			builder.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		if !dash && builder.Len() != 0 {
+			builder.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := strings.Trim(builder.String(), "-")
+	if len(slug) > maxLength {
+		slug = strings.TrimRight(slug[:maxLength], "-")
+	}
+
+	if slug == "" {
+		return "kingdom"
+	}
+
+	return slug
+}
+
+// Unique returns base itself or base with the smallest numeric suffix that is
+// not reserved and that taken reports as free.
+func Unique(base string, taken func(slug string) (bool, error)) (string, error) {
+	candidate := base
+
+	for i := 2; ; i++ {
+		if reserved[candidate] {
+			candidate = base + "-" + strconv.Itoa(i)
+			continue
+		}
+
+		isTaken, err := taken(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !isTaken {
+			return candidate, nil
+		}
+
+		candidate = base + "-" + strconv.Itoa(i)
+	}
+}
@@ -0,0 +1,77 @@
+package slug
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Черниговское княжество", "chernigovskoe-knyazhestvo"},
+		{"Київська Русь", "kiyivska-rus"},
+		{"  Щит и Ёж!  ", "shchit-i-ezh"},
+		{"Rus' 862", "rus-862"},
+		{"Подъём", "podem"},
+		{"---", "kingdom"},
+		{"", "kingdom"},
+		{strings.Repeat("а", 150), strings.Repeat("a", maxLength)},
+		{strings.Repeat("а ", 100), strings.TrimRight(strings.Repeat("a-", 70), "-")},
+	}
+
+	for _, tt := range tests {
+		if got := Make(tt.name); got != tt.want {
+			t.Errorf("Make(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnique(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		taken []string
+		want  string
+	}{
+		{"free", "kiev", nil, "kiev"},
+		{"taken", "kiev", []string{"kiev"}, "kiev-2"},
+		{"several taken", "kiev", []string{"kiev", "kiev-2", "kiev-3"}, "kiev-4"},
+		{"route literal", "export", nil, "export-2"},
+		{"snapshot index", "index", nil, "index-2"},
+		{"reserved and taken", "translations", []string{"translations-2"}, "translations-3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Unique(tt.base, func(candidate string) (bool, error) {
+				for _, taken := range tt.taken {
+					if taken == candidate {
+						return true, nil
+					}
+				}
+
+				return false, nil
+			})
+			if err != nil {
+				t.Fatalf("Unique(%q) error: %v", tt.base, err)
+			}
+			if got != tt.want {
+				t.Errorf("Unique(%q) = %q, want %q", tt.base, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueError(t *testing.T) {
+	lookup := errors.New("lookup failed")
+
+	_, err := Unique("kiev", func(string) (bool, error) {
+		return false, lookup
+	})
+	if !errors.Is(err, lookup) {
+		t.Errorf("Unique() error = %v, want %v", err, lookup)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -17,6 +18,7 @@ import (
 	config "kingdoms/internal/config"
 	"kingdoms/internal/database/connect"
 	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
 	role "kingdoms/internal/server/app/userRole"
 	"kingdoms/internal/server/models/responseModels"
 	"kingdoms/internal/server/models/serverModels"
@@ -27,7 +29,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
-	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 const ASYNC_KEY = "secret"
@@ -45,7 +47,7 @@ func New(ctx context.Context) (*Application, error) {
 		return nil, err
 	}
 
-	repo, err := processing.New(connect.FromEnv())
+	repo, err := processing.New(connect.FromEnv(), cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -77,21 +79,60 @@ func (a *Application) StartServer() {
 
 	a.r.GET("kingdoms", a.getKingdomsFeed)
 	a.r.GET("kingdom", a.getKingdom)
+	a.r.GET("kingdoms/:slug", a.getKingdomBySlug)
+	a.r.GET("kingdoms/export", a.exportKingdoms)
 	a.r.GET("applications", a.getAllApplications)
+	a.r.GET("applications/conflicts", a.getClaimConflicts)
+	a.r.GET("applications/queue/locks", a.getApplicationLocks)
+	a.r.GET("applications/trash", a.getTrash)
+	a.r.GET("admin/drafts", a.getDraftsOverview)
+	a.r.GET("notifications", a.getNotifications)
+	a.r.GET("invitations", a.getInvitations)
 	a.r.GET("application/with_kingdoms", a.getApplicationWithKingdoms)
+	a.r.GET("application/states", a.getApplicationStates)
+	a.r.GET("application/:id/history", a.getApplicationHistory)
+	a.r.GET("application/:id/snapshot", a.getApplicationSnapshot)
+	a.r.GET("application/:id/approvals", a.getApplicationApprovals)
+	a.r.GET("application/:id/coauthors", a.getCoAuthors)
+	a.r.GET("application/:id/comments", a.getApplicationComments)
+	a.r.GET("application/:id/attachments", a.getAttachments)
+	a.r.GET("application/:id/attachments/:attachmentId", a.downloadAttachment)
+	a.r.GET("application/:id/charter.pdf", a.getCharter)
+	a.r.GET("charter/:code/verify", a.verifyCharter)
+	a.r.GET("kingdom/translations", a.getKingdomTranslations)
+	a.r.GET("kingdom/:id/availability", a.getKingdomAvailability)
+	a.r.GET("kingdoms/translations/missing", a.getMissingTranslations)
 
 	a.r.POST("kingdom/create", a.createKingdom)
+	a.r.POST("kingdoms/import", a.importKingdoms)
+	a.r.POST("applications/queue/next", a.takeNextApplication)
+	a.r.POST("applications/decisions", a.decideApplications)
+	a.r.POST("application/:id/comments", a.createApplicationComment)
+	a.r.POST("application/:id/clone", a.cloneApplication)
+	a.r.POST("application/:id/restore", a.restoreApplication)
+	a.r.POST("application/:id/attachments", a.uploadAttachment)
+	a.r.POST("application/:id/coauthors", a.inviteCoAuthor)
 
 	a.r.PUT("kingdom/update", a.updateKingdom)
 	a.r.PUT("kingdom/update/status", a.updateKingdomStatus)
+	a.r.PUT("kingdom/translation", a.updateKingdomTranslation)
 	a.r.PUT("application/status/user", a.updateApplicationStatusUser)
 	a.r.PUT("application/status/moderator", a.updateApplicationStatusModerator)
 	a.r.PUT("application/update", a.updateApplication)
+	a.r.PUT("user/timezone", a.updateUserTimeZone)
 	a.r.PUT("application/add_kingdom", a.addKingdomToApplication)
 	a.r.PUT("application/update_kingdom", a.updateKingdomFromApplication)
+	a.r.PUT("application/:id/comments/:commentId", a.updateApplicationComment)
+	a.r.PUT("application/:id/lines/:lineId/decision", a.decideApplicationLine)
+	a.r.PUT("invitations/:id", a.answerInvitation)
 
 	a.r.DELETE("application/delete_kingdom", a.deleteKingdomFromApplication)
 	a.r.DELETE("application/delete", a.deleteApplication)
+	a.r.DELETE("applications/queue/:id", a.releaseApplication)
+	a.r.DELETE("kingdom/translation", a.deleteKingdomTranslation)
+	a.r.DELETE("application/:id/comments/:commentId", a.deleteApplicationComment)
+	a.r.DELETE("application/:id/attachments/:attachmentId", a.deleteAttachment)
+	a.r.DELETE("application/:id/coauthors/:userId", a.removeCoAuthor)
 
 	a.r.PUT("async/application", a.asyncPutApplicationInfo)
 
@@ -100,6 +141,9 @@ func (a *Application) StartServer() {
 	a.r.POST("signup", a.signup)
 	a.r.DELETE("logout", a.logout)
 
+	go a.runTrashPurge()
+	go a.runDraftExpiry()
+
 	a.r.Run(":8000")
 
 	log.Println("Server is down")
@@ -175,6 +219,7 @@ func (a *Application) sendRequestToAsyncServer(applicationToSend processing.Asyn
 
 func (a *Application) getKingdomsFeed(ctx *gin.Context) {
 	kingdomName := ctx.Query("Kingdom_name")
+	loc := requestLocale(ctx)
 
 	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
 	if response != (responseModels.ResponseDefault{}) {
@@ -192,6 +237,20 @@ func (a *Application) getKingdomsFeed(ctx *gin.Context) {
 			return
 		}
 
+		err = a.repo.TranslateKingdoms(kingdoms, loc)
+		if err != nil {
+			response := responseModels.ResponseDefault{
+				Code:    500,
+				Status:  "error",
+				Message: "error translating kingdoms: " + err.Error(),
+				Body:    nil,
+			}
+
+			ctx.JSON(http.StatusInternalServerError, response)
+
+			return
+		}
+
 		response := responseModels.ResponseDefault{
 			Code:    200,
 			Status:  "ok",
@@ -217,6 +276,20 @@ func (a *Application) getKingdomsFeed(ctx *gin.Context) {
 		return
 	}
 
+	err = a.repo.TranslateKingdoms(kingdoms, loc)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error translating kingdoms: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+
+		return
+	}
+
 	user, err := a.repo.GetUserByName(myClaims.Name)
 	if err != nil {
 		response := responseModels.ResponseDefault{
@@ -283,6 +356,20 @@ func (a *Application) getKingdom(ctx *gin.Context) {
 		return
 	}
 
+	translated := []schema.Kingdom{kingdom}
+	err = a.repo.TranslateKingdoms(translated, requestLocale(ctx))
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error translating kingdom: " + err.Error(),
+			Body:    nil,
+		}
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+	kingdom = translated[0]
+
 	response := responseModels.ResponseDefault{
 		Code:    200,
 		Status:  "ok",
@@ -293,6 +380,58 @@ func (a *Application) getKingdom(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, response)
 }
 
+func (a *Application) getKingdomBySlug(ctx *gin.Context) {
+	kingdom, currentSlug, err := a.repo.GetKingdomBySlug(ctx.Param("slug"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response := responseModels.ResponseDefault{
+			Code:    404,
+			Status:  "error",
+			Message: "error getting necessary kingdom: " + err.Error(),
+			Body:    nil,
+		}
+		ctx.JSON(http.StatusNotFound, response)
+		return
+	}
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting necessary kingdom: " + err.Error(),
+			Body:    nil,
+		}
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	if currentSlug != "" {
+		location := url.URL{Path: "/kingdoms/" + currentSlug, RawQuery: ctx.Request.URL.RawQuery}
+		ctx.Redirect(http.StatusMovedPermanently, location.String())
+		return
+	}
+
+	translated := []schema.Kingdom{kingdom}
+	err = a.repo.TranslateKingdoms(translated, requestLocale(ctx))
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error translating kingdom: " + err.Error(),
+			Body:    nil,
+		}
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	response := responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "kingdom found",
+		Body:    translated[0],
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
 func (a *Application) createKingdom(ctx *gin.Context) {
 	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
 	if response != (responseModels.ResponseDefault{}) {
@@ -499,6 +638,19 @@ func (a *Application) getAllApplications(ctx *gin.Context) {
 		return
 	}
 
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
 	all := ctx.Query("All")
 
 	if all != "true" {
@@ -506,13 +658,14 @@ func (a *Application) getAllApplications(ctx *gin.Context) {
 
 		applications, err := a.repo.GetApplications(*user, applicationId)
 		if err != nil {
+			code := applicationErrorCode(err)
 			response := responseModels.ResponseDefault{
-				Code:    500,
+				Code:    code,
 				Status:  "error",
 				Message: "error getting necessary applications: " + err.Error(),
 				Body:    nil,
 			}
-			ctx.JSON(http.StatusInternalServerError, response)
+			ctx.JSON(code, response)
 			return
 		}
 
@@ -520,78 +673,226 @@ func (a *Application) getAllApplications(ctx *gin.Context) {
 			Code:    200,
 			Status:  "ok",
 			Message: "applications found",
-			Body:    applications,
+			Body:    inZone(applications, zone),
 		}
 
 		ctx.JSON(http.StatusOK, response)
 		return
 	}
 
-	fromStr := strings.Split(ctx.Query("From"), "T")[0]
-	toStr := strings.Split(ctx.Query("To"), "T")[0]
+	params, err := parseApplicationsQuery(ctx, zone)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing applications query: " + err.Error(),
+			Body:    nil,
+		}
 
-	var from time.Time
-	var to time.Time
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
 
-	if fromStr != "" {
-		from, err = time.Parse("2006-01-02", fromStr)
-		if err != nil {
-			response := responseModels.ResponseDefault{
-				Code:    400,
-				Status:  "error",
-				Message: "error parsing dateFrom: " + err.Error(),
-				Body:    nil,
-			}
+	applications, err := a.repo.GetAllApplications(*user, params)
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error getting necessary applications: " + err.Error(),
+			Body:    nil,
+		}
+		ctx.JSON(code, response)
+		return
+	}
 
-			ctx.JSON(http.StatusBadRequest, response)
-			return
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "applications found",
+		Body:    inZone(applications, zone),
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (a *Application) getApplicationWithKingdoms(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
 		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
 	}
 
-	if toStr != "" {
-		to, err = time.Parse("2006-01-02", toStr)
-		if err != nil {
-			response := responseModels.ResponseDefault{
-				Code:    400,
-				Status:  "error",
-				Message: "error parsing dateTo: " + err.Error(),
-				Body:    nil,
-			}
+	applicationId := ctx.Query("Id")
+	if applicationId == "" {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error no id provided",
+			Body:    nil,
+		}
 
-			ctx.JSON(http.StatusBadRequest, response)
-			return
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	application, err := a.repo.GetApplicationWithKingdoms(*user, applicationId)
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error getting necessary kingdoms from application: " + err.Error(),
+			Body:    nil,
 		}
+		ctx.JSON(code, response)
+		return
 	}
 
-	params := processing.StructGetAllApplications{
-		Status: ctx.Query("Status"),
-		From:   datatypes.Date(from),
-		To:     datatypes.Date(to),
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "kingdoms from application found",
+		Body:    inZone(application, zone),
 	}
 
-	applications, err := a.repo.GetAllApplications(params)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// getApplicationStates describes every state in the requested locale along
+// with the transitions out of it and the roles allowed to make them.
+func (a *Application) getApplicationStates(ctx *gin.Context) {
+	loc := requestLocale(ctx)
+
+	type transitionInfo struct {
+		To      appState.State
+		ByOwner bool
+		Roles   []role.Role
+	}
+
+	type stateInfo struct {
+		Code        appState.State
+		Label       string
+		Transitions []transitionInfo
+	}
+
+	states := make([]stateInfo, 0, len(appState.All))
+	for _, state := range appState.All {
+		info := stateInfo{
+			Code:        state,
+			Label:       state.Label(loc),
+			Transitions: []transitionInfo{},
+		}
+
+		for _, transition := range appState.Transitions {
+			if transition.From == state {
+				info.Transitions = append(info.Transitions, transitionInfo{
+					To:      transition.To,
+					ByOwner: transition.ByOwner,
+					Roles:   transition.Roles,
+				})
+			}
+		}
+
+		states = append(states, info)
+	}
+
+	response := responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "application states found",
+		Body:    states,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (a *Application) getApplicationHistory(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
 	if err != nil {
 		response := responseModels.ResponseDefault{
 			Code:    500,
 			Status:  "error",
-			Message: "error getting necessary applications: " + err.Error(),
+			Message: "error getting user by name: " + err.Error(),
 			Body:    nil,
 		}
+
 		ctx.JSON(http.StatusInternalServerError, response)
 		return
 	}
 
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	history, err := a.repo.GetApplicationHistory(*user, ctx.Param("id"))
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error getting application history: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
 	response = responseModels.ResponseDefault{
 		Code:    200,
 		Status:  "ok",
-		Message: "applications found",
-		Body:    applications,
+		Message: "application history found",
+		Body:    inZone(history, zone),
 	}
 
 	ctx.JSON(http.StatusOK, response)
 }
 
-func (a *Application) getApplicationWithKingdoms(ctx *gin.Context) {
+// getApplicationSnapshot shows what was submitted and what has changed in
+// live data since.
+func (a *Application) getApplicationSnapshot(ctx *gin.Context) {
 	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
 	if response != (responseModels.ResponseDefault{}) {
 		ctx.JSON(response.Code, response)
@@ -611,12 +912,12 @@ func (a *Application) getApplicationWithKingdoms(ctx *gin.Context) {
 		return
 	}
 
-	applicationId := ctx.Query("Id")
-	if applicationId == "" {
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
 		response := responseModels.ResponseDefault{
 			Code:    400,
 			Status:  "error",
-			Message: "error no id provided",
+			Message: "error parsing time zone: " + err.Error(),
 			Body:    nil,
 		}
 
@@ -624,23 +925,25 @@ func (a *Application) getApplicationWithKingdoms(ctx *gin.Context) {
 		return
 	}
 
-	application, err := a.repo.GetApplicationWithKingdoms(*user, applicationId)
+	snapshot, err := a.repo.GetApplicationSnapshot(*user, ctx.Param("id"))
 	if err != nil {
+		code := applicationErrorCode(err)
 		response := responseModels.ResponseDefault{
-			Code:    500,
+			Code:    code,
 			Status:  "error",
-			Message: "error getting necessary kingdoms from application: " + err.Error(),
+			Message: "error getting application snapshot: " + err.Error(),
 			Body:    nil,
 		}
-		ctx.JSON(http.StatusInternalServerError, response)
+
+		ctx.JSON(code, response)
 		return
 	}
 
 	response = responseModels.ResponseDefault{
 		Code:    200,
 		Status:  "ok",
-		Message: "kingdoms from application found",
-		Body:    application,
+		Message: "application snapshot found",
+		Body:    inZone(snapshot, zone),
 	}
 
 	ctx.JSON(http.StatusOK, response)
@@ -666,6 +969,19 @@ func (a *Application) createApplication(ctx *gin.Context) {
 		return
 	}
 
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
 	var applicationToAdd processing.KingdomAddToApplication
 	if err := ctx.BindJSON(&applicationToAdd); err != nil {
 		response := responseModels.ResponseDefault{
@@ -696,14 +1012,15 @@ func (a *Application) createApplication(ctx *gin.Context) {
 
 	applicationToReturn, err := a.repo.AddKingdomToApplication(*user, applicationToAdd)
 	if err != nil {
+		code := applicationErrorCode(err)
 		response := responseModels.ResponseDefault{
-			Code:    500,
+			Code:    code,
 			Status:  "error",
 			Message: "error adding kingdom to application: " + err.Error(),
 			Body:    applicationToReturn,
 		}
 
-		ctx.JSON(http.StatusInternalServerError, response)
+		ctx.JSON(code, response)
 		return
 	}
 
@@ -711,13 +1028,14 @@ func (a *Application) createApplication(ctx *gin.Context) {
 		strconv.Itoa(int(applicationToAdd.ApplicationId)))
 
 	if err != nil {
+		code := applicationErrorCode(err)
 		response := responseModels.ResponseDefault{
-			Code:    500,
+			Code:    code,
 			Status:  "error",
 			Message: "error getting necessary kingdoms from application: " + err.Error(),
 			Body:    nil,
 		}
-		ctx.JSON(http.StatusInternalServerError, response)
+		ctx.JSON(code, response)
 		return
 	}
 
@@ -725,7 +1043,7 @@ func (a *Application) createApplication(ctx *gin.Context) {
 		Code:    200,
 		Status:  "ok",
 		Message: "kingdoms from application found",
-		Body:    applicationWithKingdoms,
+		Body:    inZone(applicationWithKingdoms, zone),
 	}
 
 	ctx.JSON(http.StatusOK, response)
@@ -766,14 +1084,15 @@ func (a *Application) updateApplicationStatusUser(ctx *gin.Context) {
 
 	application4Async, err := a.repo.UpdateApplicationStatusUser(*user, applicationToUpdate)
 	if err != nil {
+		code := applicationErrorCode(err)
 		response := responseModels.ResponseDefault{
-			Code:    500,
+			Code:    code,
 			Status:  "error",
 			Message: "error updating application status: " + err.Error(),
 			Body:    nil,
 		}
 
-		ctx.JSON(http.StatusInternalServerError, response)
+		ctx.JSON(code, response)
 		return
 	}
 
@@ -828,19 +1147,29 @@ func (a *Application) updateApplicationStatusModerator(ctx *gin.Context) {
 		return
 	}
 
-	err = a.repo.UpdateApplicationStatusModerator(*user, applicationToUpdate)
+	taken, err := a.holdApplicationLock(ctx, *user, applicationToUpdate.Id)
+	if err == nil {
+		err = a.repo.UpdateApplicationStatusModerator(*user, applicationToUpdate)
+		if err != nil && taken {
+			_, _ = a.redis.UnlockApplication(ctx, applicationToUpdate.Id, user.Name)
+		}
+	}
 	if err != nil {
+		code := applicationErrorCode(err)
 		response := responseModels.ResponseDefault{
-			Code:    500,
+			Code:    code,
 			Status:  "error",
 			Message: "error updating application status: " + err.Error(),
 			Body:    nil,
 		}
 
-		ctx.JSON(http.StatusInternalServerError, response)
+		ctx.JSON(code, response)
 		return
 	}
 
+	// the decision is made, so the application no longer needs its queue lock
+	_, _ = a.redis.UnlockApplication(ctx, applicationToUpdate.Id, user.Name)
+
 	response = responseModels.ResponseDefault{
 		Code:    200,
 		Status:  "ok",
@@ -886,14 +1215,15 @@ func (a *Application) updateApplication(ctx *gin.Context) {
 
 	err = a.repo.UpdateApplication(*user, applicationToUpdate)
 	if err != nil {
+		code := applicationErrorCode(err)
 		response := responseModels.ResponseDefault{
-			Code:    500,
+			Code:    code,
 			Status:  "error",
 			Message: "error updating application ruler: " + err.Error(),
 			Body:    nil,
 		}
 
-		ctx.JSON(http.StatusInternalServerError, response)
+		ctx.JSON(code, response)
 		return
 	}
 
@@ -927,6 +1257,19 @@ func (a *Application) addKingdomToApplication(ctx *gin.Context) {
 		return
 	}
 
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
 	var kingdomAddToApplication processing.KingdomAddToApplication
 	if err := ctx.BindJSON(&kingdomAddToApplication); err != nil {
 		response := responseModels.ResponseDefault{
@@ -942,14 +1285,15 @@ func (a *Application) addKingdomToApplication(ctx *gin.Context) {
 
 	applicationToReturn, err := a.repo.AddKingdomToApplication(*user, kingdomAddToApplication)
 	if err != nil {
+		code := applicationErrorCode(err)
 		response := responseModels.ResponseDefault{
-			Code:    500,
+			Code:    code,
 			Status:  "error",
 			Message: "error adding kingdom to application: " + err.Error(),
-			Body:    nil,
+			Body:    a.periodSuggestion(err, kingdomAddToApplication),
 		}
 
-		ctx.JSON(http.StatusInternalServerError, response)
+		ctx.JSON(code, response)
 		return
 	}
 
@@ -957,7 +1301,7 @@ func (a *Application) addKingdomToApplication(ctx *gin.Context) {
 		Code:    200,
 		Status:  "ok",
 		Message: "kingdom added to application successfully",
-		Body:    applicationToReturn,
+		Body:    inZone(applicationToReturn, zone),
 	}
 
 	ctx.JSON(http.StatusOK, response)
@@ -983,6 +1327,19 @@ func (a *Application) updateKingdomFromApplication(ctx *gin.Context) {
 		return
 	}
 
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
 	var updateKingdomFromApplication processing.KingdomAddToApplication
 	if err := ctx.BindJSON(&updateKingdomFromApplication); err != nil {
 		response := responseModels.ResponseDefault{
@@ -998,14 +1355,15 @@ func (a *Application) updateKingdomFromApplication(ctx *gin.Context) {
 
 	applicationToReturn, err := a.repo.UpdateKingdomFromApplication(*user, updateKingdomFromApplication)
 	if err != nil {
+		code := applicationErrorCode(err)
 		response := responseModels.ResponseDefault{
-			Code:    500,
+			Code:    code,
 			Status:  "error",
 			Message: "error adding kingdom to application: " + err.Error(),
-			Body:    nil,
+			Body:    a.periodSuggestion(err, updateKingdomFromApplication),
 		}
 
-		ctx.JSON(http.StatusInternalServerError, response)
+		ctx.JSON(code, response)
 		return
 	}
 
@@ -1013,7 +1371,7 @@ func (a *Application) updateKingdomFromApplication(ctx *gin.Context) {
 		Code:    200,
 		Status:  "ok",
 		Message: "kingdom added to application successfully",
-		Body:    applicationToReturn,
+		Body:    inZone(applicationToReturn, zone),
 	}
 
 	ctx.JSON(http.StatusOK, response)
@@ -1054,14 +1412,15 @@ func (a *Application) deleteKingdomFromApplication(ctx *gin.Context) {
 
 	err = a.repo.DeleteKingdomFromApplication(*user, kingdomToDeleteFromApplication)
 	if err != nil {
+		code := applicationErrorCode(err)
 		response := responseModels.ResponseDefault{
-			Code:    500,
+			Code:    code,
 			Status:  "error",
 			Message: "error deleting kingdom from application: " + err.Error(),
 			Body:    nil,
 		}
 
-		ctx.JSON(http.StatusInternalServerError, response)
+		ctx.JSON(code, response)
 		return
 	}
 
@@ -1110,14 +1469,15 @@ func (a *Application) deleteApplication(ctx *gin.Context) {
 
 	err = a.repo.DeleteApplication(*user, applicatinToDelete)
 	if err != nil {
+		code := applicationErrorCode(err)
 		response := responseModels.ResponseDefault{
-			Code:    500,
+			Code:    code,
 			Status:  "error",
 			Message: "error deleting application: " + err.Error(),
 			Body:    nil,
 		}
 
-		ctx.JSON(http.StatusInternalServerError, response)
+		ctx.JSON(code, response)
 		return
 	}
 
@@ -0,0 +1,155 @@
+package appState
+
+import (
+	"errors"
+
+	"kingdoms/internal/server/app/locale"
+	role "kingdoms/internal/server/app/userRole"
+)
+
+// State is the stable code of an application state. Codes are what the
+// database stores and the API accepts; labels are only for display.
+type State string
+
+const (
+	Draft     State = "draft"
+	Submitted State = "submitted"
+	Approved  State = "approved"
+	Rejected  State = "rejected"
+	Deleted   State = "deleted"
+)
+
+var All = [...]State{Draft, Submitted, Approved, Rejected, Deleted}
+
+var labels = map[State]map[locale.Locale]string{
+	Draft:     {locale.Ru: "В разработке", locale.En: "Draft", locale.Uk: "В розробці"},
+	Submitted: {locale.Ru: "Сформирована", locale.En: "Submitted", locale.Uk: "Сформована"},
+	Approved:  {locale.Ru: "Завершена", locale.En: "Approved", locale.Uk: "Завершена"},
+	Rejected:  {locale.Ru: "Отклонена", locale.En: "Rejected", locale.Uk: "Відхилена"},
+	Deleted:   {locale.Ru: "Удалена", locale.En: "Deleted", locale.Uk: "Видалена"},
+}
+
+// legacyLabels are the Russian strings stored and sent before states got
+// codes, other than the current Russian labels.
+var legacyLabels = map[string]State{
+	"На рассмотрении": Submitted,
+	"Одобрена":        Approved,
+}
+
+var (
+	ErrUnknownState        = errors.New("unknown application state")
+	ErrIllegalTransition   = errors.New("illegal application state transition")
+	ErrForbiddenTransition = errors.New("application state transition is not allowed for this user")
+)
+
+func (s State) Valid() bool {
+	_, ok := labels[s]
+	return ok
+}
+
+func (s State) Label(loc locale.Locale) string {
+	if label, ok := labels[s][loc]; ok {
+		return label
+	}
+
+	return labels[s][locale.Default]
+}
+
+// Parse accepts a state code as well as any of its labels, which is what
+// older clients send.
+func Parse(str string) (State, error) {
+	if State(str).Valid() {
+		return State(str), nil
+	}
+
+	for state, stateLabels := range labels {
+		for _, label := range stateLabels {
+			if label == str {
+				return state, nil
+			}
+		}
+	}
+
+	if state, ok := legacyLabels[str]; ok {
+		return state, nil
+	}
+
+	return "", ErrUnknownState
+}
+
+// LegacyLabels lists every label a state could be stored with before codes
+// were introduced.
+func LegacyLabels(s State) []string {
+	stateLabels := []string{labels[s][locale.Ru]}
+	for label, state := range legacyLabels {
+		if state == s {
+			stateLabels = append(stateLabels, label)
+		}
+	}
+
+	return stateLabels
+}
+
+type Transition struct {
+	From State
+	To   State
+	// ByOwner transitions are made by the creator of the application on their
+	// own application, the others by a moderator on anyone's.
+	ByOwner bool
+	Roles   []role.Role
+
+	SetsDateSend     bool
+	SetsDateComplete bool
+	// Restores take an application out of the trash. Only restoring it makes
+	// them, since that also checks the restore window and the single draft.
+	Restores bool
+}
+
+var everyone = []role.Role{role.Buyer, role.Manager, role.Admin}
+var moderators = []role.Role{role.Manager, role.Admin}
+var admins = []role.Role{role.Admin}
+
+var Transitions = []Transition{
+	{From: Draft, To: Submitted, ByOwner: true, Roles: everyone, SetsDateSend: true},
+	{From: Draft, To: Deleted, ByOwner: true, Roles: everyone},
+	{From: Submitted, To: Approved, Roles: moderators, SetsDateComplete: true},
+	{From: Submitted, To: Rejected, Roles: moderators, SetsDateComplete: true},
+	{From: Approved, To: Submitted, Roles: moderators},
+	{From: Rejected, To: Submitted, Roles: moderators},
+	{From: Deleted, To: Draft, ByOwner: true, Roles: everyone, Restores: true},
+	{From: Deleted, To: Draft, Roles: admins, Restores: true},
+}
+
+func (t Transition) Allows(userRole role.Role) bool {
+	for _, allowed := range t.Roles {
+		if allowed == userRole {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check finds the transition from one state to another made by a user with
+// userRole, either as the owner or as a moderator. The same move may be listed
+// once for owners and once for moderators.
+func Check(from State, to State, userRole role.Role, byOwner bool) (Transition, error) {
+	if !to.Valid() {
+		return Transition{}, ErrUnknownState
+	}
+
+	err := ErrIllegalTransition
+	for _, transition := range Transitions {
+		if transition.From != from || transition.To != to {
+			continue
+		}
+
+		if transition.ByOwner == byOwner && transition.Allows(userRole) {
+			return transition, nil
+		}
+
+		err = ErrForbiddenTransition
+	}
+
+	return Transition{}, err
+}
@@ -0,0 +1,106 @@
+package appState
+
+import (
+	"errors"
+	"testing"
+
+	role "kingdoms/internal/server/app/userRole"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    State
+		to      State
+		role    role.Role
+		byOwner bool
+		wantErr error
+	}{
+		{"owner submits draft", Draft, Submitted, role.Buyer, true, nil},
+		{"owner deletes draft", Draft, Deleted, role.Buyer, true, nil},
+		{"manager approves", Submitted, Approved, role.Manager, false, nil},
+		{"admin rejects", Submitted, Rejected, role.Admin, false, nil},
+		{"manager reopens approved", Approved, Submitted, role.Manager, false, nil},
+		{"manager reopens rejected", Rejected, Submitted, role.Manager, false, nil},
+		{"buyer cannot approve", Submitted, Approved, role.Buyer, false, ErrForbiddenTransition},
+		{"owner cannot approve own", Submitted, Approved, role.Manager, true, ErrForbiddenTransition},
+		{"moderator cannot submit for owner", Draft, Submitted, role.Manager, false, ErrForbiddenTransition},
+		{"unknown role cannot submit", Draft, Submitted, role.Unknown, true, ErrForbiddenTransition},
+		{"owner cannot delete submitted", Submitted, Deleted, role.Buyer, true, ErrIllegalTransition},
+		{"owner cannot delete approved", Approved, Deleted, role.Buyer, true, ErrIllegalTransition},
+		{"draft cannot be approved", Draft, Approved, role.Admin, false, ErrIllegalTransition},
+		{"owner restores", Deleted, Draft, role.Buyer, true, nil},
+		{"admin restores", Deleted, Draft, role.Admin, false, nil},
+		{"admin restores own", Deleted, Draft, role.Admin, true, nil},
+		{"manager cannot restore", Deleted, Draft, role.Manager, false, ErrForbiddenTransition},
+		{"restored only to draft", Deleted, Submitted, role.Admin, false, ErrIllegalTransition},
+		{"same state", Submitted, Submitted, role.Admin, false, ErrIllegalTransition},
+		{"unknown target", Draft, State("archived"), role.Buyer, true, ErrUnknownState},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transition, err := Check(tt.from, tt.to, tt.role, tt.byOwner)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check(%s, %s) error = %v, want %v", tt.from, tt.to, err, tt.wantErr)
+			}
+			if err == nil && (transition.From != tt.from || transition.To != tt.to) {
+				t.Errorf("Check(%s, %s) = %s -> %s", tt.from, tt.to, transition.From, transition.To)
+			}
+		})
+	}
+}
+
+func TestCheckDates(t *testing.T) {
+	submit, err := Check(Draft, Submitted, role.Buyer, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !submit.SetsDateSend || submit.SetsDateComplete {
+		t.Errorf("submission sets DateSend %v, DateComplete %v", submit.SetsDateSend, submit.SetsDateComplete)
+	}
+
+	reopen, err := Check(Approved, Submitted, role.Manager, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopen.SetsDateSend || reopen.SetsDateComplete {
+		t.Errorf("reopening sets DateSend %v, DateComplete %v", reopen.SetsDateSend, reopen.SetsDateComplete)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    State
+		wantErr bool
+	}{
+		{"draft", Draft, false},
+		{"approved", Approved, false},
+		{"В разработке", Draft, false},
+		{"Submitted", Submitted, false},
+		{"Відхилена", Rejected, false},
+		{"На рассмотрении", Submitted, false},
+		{"Одобрена", Approved, false},
+		{"", "", true},
+		{"DRAFT", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.str)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Parse(%q) = %q, %v, want %q", tt.str, got, err, tt.want)
+		}
+	}
+}
+
+func TestTransitionsUseKnownStates(t *testing.T) {
+	for _, transition := range Transitions {
+		if !transition.From.Valid() || !transition.To.Valid() {
+			t.Errorf("transition %s -> %s uses an unknown state", transition.From, transition.To)
+		}
+		if len(transition.Roles) == 0 {
+			t.Errorf("transition %s -> %s allows no role", transition.From, transition.To)
+		}
+	}
+}
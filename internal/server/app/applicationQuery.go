@@ -0,0 +1,115 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/processing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseApplicationsQuery reads the filters of GET applications?All=true.
+// Status may be repeated or comma separated; From and To are the older names
+// of SentFrom and SentTo. Dates without an offset are read in zone.
+func parseApplicationsQuery(ctx *gin.Context, zone *time.Location) (processing.StructGetAllApplications, error) {
+	var params processing.StructGetAllApplications
+	var err error
+
+	for _, value := range ctx.QueryArray("Status") {
+		for _, str := range strings.Split(value, ",") {
+			if str = strings.TrimSpace(str); str == "" {
+				continue
+			}
+
+			state, err := appState.Parse(str)
+			if err != nil {
+				return params, err
+			}
+
+			params.States = append(params.States, state)
+		}
+	}
+
+	ids := []struct {
+		name string
+		dst  *uint
+	}{
+		{"Creator", &params.Creator},
+		{"Moderator", &params.Moderator},
+		{"Kingdom", &params.Kingdom},
+	}
+	for _, id := range ids {
+		if ctx.Query(id.name) == "" {
+			continue
+		}
+
+		value, err := strconv.ParseUint(ctx.Query(id.name), 10, 32)
+		if err != nil {
+			return params, fmt.Errorf("%s must be an id", id.name)
+		}
+
+		*id.dst = uint(value)
+	}
+
+	if ctx.Query("Check") != "" {
+		check, err := strconv.ParseBool(ctx.Query("Check"))
+		if err != nil {
+			return params, fmt.Errorf("Check must be true or false")
+		}
+
+		params.Check = &check
+	}
+
+	dates := []struct {
+		name  string
+		dst   *time.Time
+		isEnd bool
+	}{
+		{"From", &params.SentFrom, false},
+		{"To", &params.SentTo, true},
+		{"SentFrom", &params.SentFrom, false},
+		{"SentTo", &params.SentTo, true},
+		{"CreatedFrom", &params.CreatedFrom, false},
+		{"CreatedTo", &params.CreatedTo, true},
+		{"CompletedFrom", &params.CompletedFrom, false},
+		{"CompletedTo", &params.CompletedTo, true},
+	}
+	for _, date := range dates {
+		str := ctx.Query(date.name)
+		if str == "" {
+			continue
+		}
+
+		value, err := parseQueryTime(str, zone, date.isEnd)
+		if err != nil {
+			return params, fmt.Errorf("%s %w", date.name, err)
+		}
+
+		*date.dst = value
+	}
+
+	params.Ruler = ctx.Query("Ruler")
+	params.Sort = ctx.Query("Sort")
+	params.Cursor = ctx.Query("Cursor")
+
+	switch strings.ToLower(ctx.Query("Order")) {
+	case "", "asc":
+	case "desc":
+		params.Desc = true
+	default:
+		return params, fmt.Errorf("Order must be asc or desc")
+	}
+
+	if ctx.Query("Limit") != "" {
+		params.Limit, err = strconv.Atoi(ctx.Query("Limit"))
+		if err != nil || params.Limit <= 0 {
+			return params, fmt.Errorf("Limit must be a positive number")
+		}
+	}
+
+	return params, nil
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"net/http"
+
+	"kingdoms/internal/server/models/responseModels"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (a *Application) getApplicationApprovals(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	approvals, err := a.repo.GetApplicationApprovals(*user, ctx.Param("id"))
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error getting application approvals: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "application approvals found",
+		Body:    inZone(approvals, zone),
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,292 @@
+package app
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"kingdoms/internal/server/models/responseModels"
+	"kingdoms/internal/server/processing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is room for the form fields and boundaries around the file.
+const multipartOverhead = 1 << 20
+
+func (a *Application) uploadAttachment(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	limit := a.config.Attachments.MaxSize + multipartOverhead
+	if ctx.Request.ContentLength > limit {
+		response := responseModels.ResponseDefault{
+			Code:    413,
+			Status:  "error",
+			Message: "error uploading attachment: " + processing.ErrAttachmentTooLarge.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusRequestEntityTooLarge, response)
+		return
+	}
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+
+	fileHeader, err := ctx.FormFile("File")
+	// a chunked body carries no length up front: the limit shows only here
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		response := responseModels.ResponseDefault{
+			Code:    413,
+			Status:  "error",
+			Message: "error uploading attachment: " + processing.ErrAttachmentTooLarge.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusRequestEntityTooLarge, response)
+		return
+	}
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing attachment: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var lineId uint64
+	if str := ctx.PostForm("LineId"); str != "" {
+		lineId, err = strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			response := responseModels.ResponseDefault{
+				Code:    400,
+				Status:  "error",
+				Message: "error parsing line id",
+				Body:    nil,
+			}
+
+			ctx.JSON(http.StatusBadRequest, response)
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error reading attachment: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+	defer file.Close()
+
+	attachment, err := a.repo.AddAttachment(*user, ctx.Param("id"), processing.AttachmentUpload{
+		LineId:  uint(lineId),
+		Name:    fileHeader.Filename,
+		Content: file,
+	})
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error uploading attachment: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "attachment uploaded successfully",
+		Body:    inZone(attachment, zone),
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (a *Application) getAttachments(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	attachments, err := a.repo.GetAttachments(*user, ctx.Param("id"))
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error getting attachments: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "attachments found",
+		Body:    inZone(attachments, zone),
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (a *Application) downloadAttachment(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	attachment, content, err := a.repo.OpenAttachment(*user, ctx.Param("id"), ctx.Param("attachmentId"))
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error getting attachment: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+	defer content.Close()
+
+	// served as a download with the stored type, so browsers do not render
+	// uploaded content in the page
+	ctx.DataFromReader(http.StatusOK, attachment.Size, attachment.MimeType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}),
+		"X-Content-Type-Options": "nosniff",
+		"X-Checksum-Sha256":      attachment.Checksum,
+	})
+}
+
+func (a *Application) deleteAttachment(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	err = a.repo.DeleteAttachment(*user, ctx.Param("id"), ctx.Param("attachmentId"))
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error deleting attachment: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "attachment deleted successfully",
+		Body:    nil,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"kingdoms/internal/server/models/responseModels"
+	"kingdoms/internal/server/processing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+func (a *Application) getKingdomAvailability(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	_, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	kingdomId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || kingdomId <= 0 {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing kingdom id",
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	from, errFrom := time.Parse("2006-01-02", ctx.Query("from"))
+	to, errTo := time.Parse("2006-01-02", ctx.Query("to"))
+	if errFrom != nil || errTo != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing period: from and to must be dates like 2006-01-02",
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	days := 0
+	if ctx.Query("days") != "" {
+		days, err = strconv.Atoi(ctx.Query("days"))
+		if err != nil || days <= 0 {
+			response := responseModels.ResponseDefault{
+				Code:    400,
+				Status:  "error",
+				Message: "error parsing days: must be a positive number",
+				Body:    nil,
+			}
+
+			ctx.JSON(http.StatusBadRequest, response)
+			return
+		}
+	}
+
+	availability, err := a.repo.GetKingdomAvailability(uint(kingdomId), datatypes.Date(from), datatypes.Date(to), days)
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error getting kingdom availability: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "kingdom availability found",
+		Body:    availability,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// periodSuggestion offers the nearest free period when a kingdom line was
+// refused because its period is already claimed.
+func (a *Application) periodSuggestion(err error, line processing.KingdomAddToApplication) *processing.FreePeriod {
+	if !errors.Is(err, processing.ErrPeriodConflict) {
+		return nil
+	}
+
+	suggestion, err := a.repo.SuggestFreePeriod(line.KingdomId, line.From, line.To)
+	if err != nil {
+		return nil
+	}
+
+	return &suggestion
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"mime"
+	"net/http"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/models/responseModels"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (a *Application) getCharter(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	pdf, err := a.repo.GetCharterPDF(*user, ctx.Param("id"))
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error getting charter: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	ctx.Header("Content-Disposition",
+		mime.FormatMediaType("inline", map[string]string{"filename": "charter-" + ctx.Param("id") + ".pdf"}))
+	ctx.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// verifyCharter is public: it is what the QR code on a printed charter opens.
+func (a *Application) verifyCharter(ctx *gin.Context) {
+	// nobody is signed in here, so only the header or query can pick the zone
+	zone, err := requestZone(ctx, schema.User{})
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	verification, err := a.repo.VerifyCharter(ctx.Param("code"))
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error verifying charter: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	response := responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "charter verified",
+		Body:    inZone(verification, zone),
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
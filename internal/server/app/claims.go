@@ -0,0 +1,59 @@
+package app
+
+import (
+	"net/http"
+
+	"kingdoms/internal/server/models/responseModels"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (a *Application) getClaimConflicts(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	haveRights, response := checkUserRights(*user)
+	if !haveRights {
+		ctx.JSON(http.StatusForbidden, response)
+		return
+	}
+
+	conflicts, err := a.repo.GetClaimConflicts(*user)
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error getting claim conflicts: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "claim conflicts found",
+		Body:    conflicts,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
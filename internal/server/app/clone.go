@@ -0,0 +1,83 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"kingdoms/internal/server/models/responseModels"
+	"kingdoms/internal/server/processing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (a *Application) cloneApplication(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// the body is optional: without it the clone keeps dates and fails on an existing draft
+	var cloneApplication processing.CloneApplication
+	if err := ctx.ShouldBindJSON(&cloneApplication); err != nil && !errors.Is(err, io.EOF) {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing clone options:" + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	cloned, err := a.repo.CloneApplication(*user, ctx.Param("id"), cloneApplication)
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error cloning application: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "application cloned successfully",
+		Body:    inZone(cloned, zone),
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
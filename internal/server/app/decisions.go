@@ -0,0 +1,145 @@
+package app
+
+import (
+	"net/http"
+
+	"kingdoms/internal/server/models/responseModels"
+	"kingdoms/internal/server/processing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type decisionResult struct {
+	Id      uint
+	Code    int
+	Status  string
+	Message string
+}
+
+func (a *Application) decideApplications(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	haveRights, response := checkUserRights(*user)
+	if !haveRights {
+		ctx.JSON(http.StatusForbidden, response)
+		return
+	}
+
+	var decision processing.BulkDecision
+	if err := ctx.BindJSON(&decision); err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing decisions:" + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	if len(decision.Ids) == 0 {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing decisions: no application ids",
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// applications locked by other moderators are left out of the transaction
+	lockErrors := make(map[uint]error)
+	taken := make(map[uint]bool)
+	held := []uint{}
+	for _, id := range decision.Ids {
+		lockTaken, err := a.holdApplicationLock(ctx, *user, id)
+		if err != nil {
+			lockErrors[id] = err
+			continue
+		}
+
+		taken[id] = lockTaken
+		held = append(held, id)
+	}
+
+	decided, err := a.repo.DecideApplications(*user, held, decision.State, decision.Comment)
+	if err != nil {
+		for id, lockTaken := range taken {
+			if lockTaken {
+				_, _ = a.redis.UnlockApplication(ctx, id, user.Name)
+			}
+		}
+
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error applying decisions: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	results := make([]decisionResult, 0, len(decision.Ids))
+	for _, id := range decision.Ids {
+		err, locked := lockErrors[id]
+		if !locked {
+			err = decided[0].Err
+			decided = decided[1:]
+		}
+
+		if err != nil {
+			if taken[id] {
+				_, _ = a.redis.UnlockApplication(ctx, id, user.Name)
+			}
+
+			results = append(results, decisionResult{
+				Id:      id,
+				Code:    applicationErrorCode(err),
+				Status:  "error",
+				Message: err.Error(),
+			})
+			continue
+		}
+
+		_, _ = a.redis.UnlockApplication(ctx, id, user.Name)
+
+		results = append(results, decisionResult{
+			Id:      id,
+			Code:    200,
+			Status:  "ok",
+			Message: "appliction status updated successfully",
+		})
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "decisions applied",
+		Body:    results,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
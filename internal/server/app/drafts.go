@@ -0,0 +1,166 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"kingdoms/internal/server/models/responseModels"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runDraftExpiry periodically warns owners of stale drafts and expires the
+// drafts they left untouched after the warning.
+func (a *Application) runDraftExpiry() {
+	if a.config.Drafts.ExpireAfterDays <= 0 || a.config.Drafts.CheckInterval <= 0 {
+		log.Println("draft expiry disabled")
+		return
+	}
+
+	ticker := time.NewTicker(a.config.Drafts.CheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		run, err := a.repo.ExpireDrafts()
+		if err != nil {
+			log.Println("draft expiry failed:", err)
+			continue
+		}
+
+		if len(run.Warned) > 0 || len(run.Expired) > 0 {
+			log.Printf("draft expiry: warned %v, expired %v", run.Warned, run.Expired)
+		}
+	}
+}
+
+func (a *Application) getDraftsOverview(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// upcoming expiries are listed for the warning period unless asked otherwise
+	days := a.config.Drafts.WarnBeforeDays
+	if ctx.Query("Days") != "" {
+		days, err = strconv.Atoi(ctx.Query("Days"))
+		if err != nil || days < 0 {
+			response := responseModels.ResponseDefault{
+				Code:    400,
+				Status:  "error",
+				Message: "error parsing days: must be a non-negative number",
+				Body:    nil,
+			}
+
+			ctx.JSON(http.StatusBadRequest, response)
+			return
+		}
+	}
+
+	overview, err := a.repo.GetDraftsOverview(*user, days)
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error getting drafts overview: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "drafts overview found",
+		Body:    inZone(overview, zone),
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (a *Application) getNotifications(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	notifications, err := a.repo.GetNotifications(*user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting notifications: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "notifications found",
+		Body:    inZone(notifications, zone),
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/processing"
+	"kingdoms/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+// applicationErrorCode maps errors returned by application mutations to the
+// HTTP status the client gets.
+func applicationErrorCode(err error) int {
+	switch {
+	case errors.Is(err, appState.ErrUnknownState),
+		errors.Is(err, processing.ErrEmptyComment),
+		errors.Is(err, processing.ErrInvalidPeriod),
+		errors.Is(err, processing.ErrInvalidQuery),
+		errors.Is(err, processing.ErrInvalidEvidence),
+		errors.Is(err, processing.ErrUnknownLineDecision),
+		errors.Is(err, processing.ErrEmptyAttachment),
+		errors.Is(err, processing.ErrInviteSelf):
+		return http.StatusBadRequest
+	case errors.Is(err, appState.ErrForbiddenTransition),
+		errors.Is(err, processing.ErrForbidden),
+		errors.Is(err, processing.ErrEditWindowClosed),
+		errors.Is(err, processing.ErrStageRole):
+		return http.StatusForbidden
+	case errors.Is(err, gorm.ErrRecordNotFound),
+		errors.Is(err, storage.ErrNotFound),
+		errors.Is(err, processing.ErrNotCoAuthor):
+		return http.StatusNotFound
+	case errors.Is(err, appState.ErrIllegalTransition),
+		errors.Is(err, processing.ErrPeriodConflict),
+		errors.Is(err, processing.ErrDraftExists),
+		errors.Is(err, processing.ErrNotInTrash),
+		errors.Is(err, processing.ErrRestoreFromTrash),
+		errors.Is(err, processing.ErrLineNotUnderReview),
+		errors.Is(err, processing.ErrNothingApproved),
+		errors.Is(err, processing.ErrNoCharter),
+		errors.Is(err, processing.ErrAlreadySigned),
+		errors.Is(err, processing.ErrNotDraft),
+		errors.Is(err, processing.ErrAlreadyInvited),
+		errors.Is(err, processing.ErrInvitationClosed):
+		return http.StatusConflict
+	case errors.Is(err, processing.ErrAttachmentTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, processing.ErrAttachmentType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, processing.ErrRestoreWindowClosed):
+		return http.StatusGone
+	case errors.Is(err, errApplicationLocked):
+		return http.StatusLocked
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/models/responseModels"
+
+	"github.com/gin-gonic/gin"
+)
+
+const exportFlushEvery = 100
+
+type kingdomExporter interface {
+	contentType() string
+	extension() string
+	begin() error
+	write(kingdom schema.Kingdom) error
+	end() error
+}
+
+func (a *Application) exportKingdoms(ctx *gin.Context) {
+	withImages := ctx.Query("Images") != "false"
+
+	var exporter kingdomExporter
+	switch ctx.DefaultQuery("Format", "ndjson") {
+	case "csv":
+		exporter = &csvKingdomExporter{writer: csv.NewWriter(ctx.Writer), withImages: withImages}
+	case "ndjson":
+		exporter = &ndjsonKingdomExporter{encoder: json.NewEncoder(ctx.Writer)}
+	case "geojson":
+		exporter = &geojsonKingdomExporter{writer: ctx.Writer}
+	default:
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error unknown export format, expected csv, ndjson or geojson",
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	started := false
+	start := func() error {
+		started = true
+
+		ctx.Header("Content-Type", exporter.contentType())
+		ctx.Header("Content-Disposition", "attachment; filename=kingdoms."+exporter.extension())
+		ctx.Status(http.StatusOK)
+
+		return exporter.begin()
+	}
+
+	exported := 0
+	err := a.repo.ExportKingdoms(ctx.Query("Kingdom_name"), withImages, requestLocale(ctx),
+		func(kingdom schema.Kingdom) error {
+			if !started {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+
+			if err := exporter.write(kingdom); err != nil {
+				return err
+			}
+
+			exported++
+			if exported%exportFlushEvery == 0 {
+				ctx.Writer.Flush()
+			}
+
+			return nil
+		})
+
+	if err != nil && !started {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error exporting kingdoms: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+	if err != nil {
+		// the status is already sent, all we can do is cut the stream short
+		log.Println("error exporting kingdoms:", err)
+		return
+	}
+
+	if !started {
+		err = start()
+	}
+	if err == nil {
+		err = exporter.end()
+	}
+	if err != nil {
+		log.Println("error exporting kingdoms:", err)
+	}
+}
+
+type csvKingdomExporter struct {
+	writer     *csv.Writer
+	withImages bool
+}
+
+func (e *csvKingdomExporter) contentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (e *csvKingdomExporter) extension() string {
+	return "csv"
+}
+
+func (e *csvKingdomExporter) begin() error {
+	header := []string{"Id", "Slug", "Name", "Area", "Capital", "Description", "State", "Latitude", "Longitude"}
+	if e.withImages {
+		header = append(header, "Image")
+	}
+
+	return e.writer.Write(header)
+}
+
+func (e *csvKingdomExporter) write(kingdom schema.Kingdom) error {
+	record := []string{
+		strconv.Itoa(int(kingdom.Id)),
+		kingdom.Slug,
+		kingdom.Name,
+		strconv.Itoa(kingdom.Area),
+		kingdom.Capital,
+		kingdom.Description,
+		kingdom.State,
+		formatCoordinate(kingdom.Latitude),
+		formatCoordinate(kingdom.Longitude),
+	}
+	if e.withImages {
+		record = append(record, kingdom.Image)
+	}
+
+	err := e.writer.Write(record)
+	if err != nil {
+		return err
+	}
+
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+func (e *csvKingdomExporter) end() error {
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+type ndjsonKingdomExporter struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonKingdomExporter) contentType() string {
+	return "application/x-ndjson"
+}
+
+func (e *ndjsonKingdomExporter) extension() string {
+	return "ndjson"
+}
+
+func (e *ndjsonKingdomExporter) begin() error {
+	return nil
+}
+
+func (e *ndjsonKingdomExporter) write(kingdom schema.Kingdom) error {
+	return e.encoder.Encode(kingdom)
+}
+
+func (e *ndjsonKingdomExporter) end() error {
+	return nil
+}
+
+// geojsonKingdomExporter writes a FeatureCollection with a point at the
+// capital of every kingdom. Kingdoms without coordinates get a null geometry,
+// which RFC 7946 allows for unlocated features.
+type geojsonKingdomExporter struct {
+	writer io.Writer
+	first  bool
+}
+
+type geojsonGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type geojsonFeature struct {
+	Type       string           `json:"type"`
+	Id         uint             `json:"id"`
+	Geometry   *geojsonGeometry `json:"geometry"`
+	Properties schema.Kingdom   `json:"properties"`
+}
+
+func (e *geojsonKingdomExporter) contentType() string {
+	return "application/geo+json"
+}
+
+func (e *geojsonKingdomExporter) extension() string {
+	return "geojson"
+}
+
+func (e *geojsonKingdomExporter) begin() error {
+	e.first = true
+
+	_, err := io.WriteString(e.writer, `{"type":"FeatureCollection","features":[`)
+
+	return err
+}
+
+func (e *geojsonKingdomExporter) write(kingdom schema.Kingdom) error {
+	feature := geojsonFeature{
+		Type:       "Feature",
+		Id:         kingdom.Id,
+		Properties: kingdom,
+	}
+	if kingdom.Latitude != nil && kingdom.Longitude != nil {
+		feature.Geometry = &geojsonGeometry{
+			Type:        "Point",
+			Coordinates: [2]float64{*kingdom.Longitude, *kingdom.Latitude},
+		}
+	}
+
+	data, err := json.Marshal(feature)
+	if err != nil {
+		return err
+	}
+
+	if !e.first {
+		data = append([]byte(","), data...)
+	}
+	e.first = false
+
+	_, err = e.writer.Write(data)
+
+	return err
+}
+
+func (e *geojsonKingdomExporter) end() error {
+	_, err := io.WriteString(e.writer, "]}")
+
+	return err
+}
+
+func formatCoordinate(coordinate *float64) string {
+	if coordinate == nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(*coordinate, 'f', -1, 64)
+}
@@ -0,0 +1,400 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/models/responseModels"
+	"kingdoms/internal/server/processing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	importFormatCSV    = "csv"
+	importFormatNDJSON = "ndjson"
+)
+
+// importKingdoms accepts either a raw CSV/NDJSON body or a multipart form with
+// the table in "file" and the images it references by file name in "images".
+func (a *Application) importKingdoms(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	haveRights, response := checkUserRights(*user)
+	if !haveRights {
+		ctx.JSON(http.StatusForbidden, response)
+		return
+	}
+
+	var rows []processing.KingdomImportRow
+
+	format := strings.ToLower(ctx.Query("Format"))
+	images := map[string]string{}
+
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			response := responseModels.ResponseDefault{
+				Code:    400,
+				Status:  "error",
+				Message: "error getting import file: " + err.Error(),
+				Body:    nil,
+			}
+
+			ctx.JSON(http.StatusBadRequest, response)
+			return
+		}
+
+		if format == "" {
+			format = importFormatFromName(file.Filename)
+		}
+
+		form, _ := ctx.MultipartForm()
+		for _, image := range form.File["images"] {
+			images[image.Filename], err = readImageDataURI(image)
+			if err != nil {
+				response := responseModels.ResponseDefault{
+					Code:    400,
+					Status:  "error",
+					Message: "error reading image " + image.Filename + ": " + err.Error(),
+					Body:    nil,
+				}
+
+				ctx.JSON(http.StatusBadRequest, response)
+				return
+			}
+		}
+
+		opened, err := file.Open()
+		if err != nil {
+			response := responseModels.ResponseDefault{
+				Code:    400,
+				Status:  "error",
+				Message: "error opening import file: " + err.Error(),
+				Body:    nil,
+			}
+
+			ctx.JSON(http.StatusBadRequest, response)
+			return
+		}
+		defer opened.Close()
+
+		rows, err = parseKingdomImport(format, opened, images)
+	} else {
+		if format == "" {
+			format = importFormatFromContentType(ctx.ContentType())
+		}
+
+		rows, err = parseKingdomImport(format, ctx.Request.Body, images)
+	}
+
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing import file: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	report, err := a.repo.ImportKingdoms(rows, ctx.Query("DryRun") == "true")
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error importing kingdoms: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	if report.Errors != 0 {
+		response := responseModels.ResponseDefault{
+			Code:    422,
+			Status:  "error",
+			Message: "kingdoms not imported: some rows have errors",
+			Body:    report,
+		}
+
+		ctx.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	message := "kingdoms imported successfully"
+	if report.DryRun {
+		message = "kingdoms import checked, nothing committed"
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: message,
+		Body:    report,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func importFormatFromName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return importFormatCSV
+	case ".ndjson", ".jsonl", ".json":
+		return importFormatNDJSON
+	}
+
+	return ""
+}
+
+func importFormatFromContentType(contentType string) string {
+	switch contentType {
+	case "text/csv":
+		return importFormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/json":
+		return importFormatNDJSON
+	}
+
+	return ""
+}
+
+func parseKingdomImport(format string, reader io.Reader, images map[string]string) ([]processing.KingdomImportRow, error) {
+	switch format {
+	case importFormatCSV:
+		return parseKingdomCSV(reader, images)
+	case importFormatNDJSON:
+		return parseKingdomNDJSON(reader, images)
+	}
+
+	return nil, errors.New("unknown import format, expected csv or ndjson")
+}
+
+func parseKingdomCSV(reader io.Reader, images map[string]string) ([]processing.KingdomImportRow, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		column := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch column {
+		case "name", "area", "capital", "image", "description", "state", "latitude", "longitude":
+			columns[column] = i
+		default:
+			return nil, errors.New("unknown column: " + name)
+		}
+	}
+
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("no name column")
+	}
+
+	var rows []processing.KingdomImportRow
+	for line := 2; ; line++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		cell := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+
+			return ""
+		}
+
+		row := processing.KingdomImportRow{Row: line}
+		row.Kingdom = schema.Kingdom{
+			Name:        cell("name"),
+			Capital:     cell("capital"),
+			Description: cell("description"),
+			State:       cell("state"),
+		}
+
+		if area := cell("area"); area != "" {
+			row.Kingdom.Area, err = strconv.Atoi(area)
+			if err != nil {
+				row.Error = "area is not a number: " + area
+			}
+		}
+
+		if row.Error == "" {
+			row.Kingdom.Latitude, err = parseImportCoordinate("latitude", cell("latitude"), 90)
+			if err != nil {
+				row.Error = err.Error()
+			}
+		}
+
+		if row.Error == "" {
+			row.Kingdom.Longitude, err = parseImportCoordinate("longitude", cell("longitude"), 180)
+			if err != nil {
+				row.Error = err.Error()
+			}
+		}
+
+		if row.Error == "" {
+			row.Kingdom.Image, err = resolveImportImage(cell("image"), images)
+			if err != nil {
+				row.Error = err.Error()
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseKingdomNDJSON(reader io.Reader, images map[string]string) ([]processing.KingdomImportRow, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var rows []processing.KingdomImportRow
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := processing.KingdomImportRow{Row: line}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		var kingdom struct {
+			Name        string
+			Area        int
+			Capital     string
+			Image       string
+			Description string
+			State       string
+			Latitude    *float64
+			Longitude   *float64
+		}
+
+		err := decoder.Decode(&kingdom)
+		if err != nil {
+			row.Error = "error parsing row: " + err.Error()
+		} else {
+			row.Kingdom = schema.Kingdom{
+				Name:        strings.TrimSpace(kingdom.Name),
+				Area:        kingdom.Area,
+				Capital:     strings.TrimSpace(kingdom.Capital),
+				Description: strings.TrimSpace(kingdom.Description),
+				State:       strings.TrimSpace(kingdom.State),
+				Latitude:    kingdom.Latitude,
+				Longitude:   kingdom.Longitude,
+			}
+
+			row.Kingdom.Image, err = resolveImportImage(strings.TrimSpace(kingdom.Image), images)
+			if err != nil {
+				row.Error = err.Error()
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, scanner.Err()
+}
+
+// parseImportCoordinate reads a coordinate cell in degrees, no further from
+// zero than limit. ParseFloat also accepts NaN and infinities, which no
+// export format can write back.
+func parseImportCoordinate(column string, value string, limit float64) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		return nil, errors.New(column + " is not a number: " + value)
+	}
+
+	if parsed < -limit || parsed > limit {
+		return nil, errors.New(column + " is out of range: " + value)
+	}
+
+	return &parsed, nil
+}
+
+// resolveImportImage turns an image cell into the data URI stored in
+// schema.Kingdom: either a name of an uploaded image or an inline data URI.
+func resolveImportImage(value string, images map[string]string) (string, error) {
+	if value == "" || strings.HasPrefix(value, "data:") {
+		return value, nil
+	}
+
+	if image, ok := images[value]; ok {
+		return image, nil
+	}
+
+	if image, ok := images[filepath.Base(value)]; ok {
+		return image, nil
+	}
+
+	return "", errors.New("image not found in upload: " + value)
+}
+
+func readImageDataURI(fileHeader *multipart.FileHeader) (string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", errors.New("not an image: " + contentType)
+	}
+
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
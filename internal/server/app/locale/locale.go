@@ -0,0 +1,84 @@
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Locale string
+
+const (
+	Ru Locale = "ru"
+	En Locale = "en"
+	Uk Locale = "uk"
+)
+
+// Default is the source locale: kingdom columns themselves are written in it,
+// every other locale is stored as a translation and falls back to it.
+const Default = Ru
+
+var Supported = [...]Locale{Ru, En, Uk}
+
+func Parse(str string) (Locale, bool) {
+	tag := strings.ToLower(strings.TrimSpace(str))
+	if i := strings.IndexAny(tag, "-_"); i != -1 {
+		tag = tag[:i]
+	}
+
+	for _, supported := range Supported {
+		if Locale(tag) == supported {
+			return supported, true
+		}
+	}
+
+	return Default, false
+}
+
+// Negotiate picks a locale from the explicit query value first and then from
+// the Accept-Language header, honouring its q-values.
+func Negotiate(query string, acceptLanguage string) Locale {
+	if loc, ok := Parse(query); ok {
+		return loc
+	}
+
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		if q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	for _, tag := range tags {
+		if loc, ok := Parse(tag.tag); ok {
+			return loc
+		}
+	}
+
+	return Default
+}
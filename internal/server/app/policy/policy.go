@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+
+	"kingdoms/internal/database/schema"
+	role "kingdoms/internal/server/app/userRole"
+)
+
+// Action is something a user does to an application or to the application
+// list as a whole.
+type Action string
+
+const (
+	ReadApplication    Action = "read application"
+	EditApplication    Action = "edit application"
+	DeleteApplication  Action = "delete application"
+	CloneApplication   Action = "clone application"
+	RestoreApplication Action = "restore application"
+	SubmitApplication  Action = "submit application"
+	DecideApplication  Action = "decide on application"
+	ReadHistory        Action = "read application history"
+	Discuss            Action = "discuss application"
+	ModerateComments   Action = "moderate comments"
+	ListApplications   Action = "list applications"
+	ManageDrafts       Action = "manage drafts"
+	ManageCoAuthors    Action = "manage co-authors"
+)
+
+// Relation is how a user stands towards an application. A user may hold
+// several relations at once, e.g. an admin who created the application.
+type Relation int
+
+const (
+	Owner Relation = 1 << iota
+	Moderator
+	Admin
+	CoAuthor
+)
+
+// rules lists, for every action, the relations that allow it.
+var rules = map[Action]Relation{
+	ReadApplication:    Owner | CoAuthor | Moderator | Admin,
+	EditApplication:    Owner | CoAuthor,
+	DeleteApplication:  Owner | Admin,
+	CloneApplication:   Owner,
+	RestoreApplication: Owner | Admin,
+	SubmitApplication:  Owner,
+	DecideApplication:  Moderator | Admin,
+	ReadHistory:        Owner | CoAuthor | Moderator | Admin,
+	Discuss:            Owner | CoAuthor | Moderator | Admin,
+	ModerateComments:   Moderator | Admin,
+	ListApplications:   Moderator | Admin,
+	ManageDrafts:       Admin,
+	ManageCoAuthors:    Owner,
+}
+
+var ErrForbidden = errors.New("insufficient rights to complete the request")
+
+// Relations returns the relations user holds towards app. app may be nil for
+// actions that do not target a single application. Co-authors are found among
+// app.CoAuthors, which callers load beforehand.
+func Relations(user schema.User, app *schema.RulerApplication) Relation {
+	var relations Relation
+
+	if app != nil && app.CreatorRefer == int(user.Id) {
+		relations |= Owner
+	}
+
+	if app != nil {
+		for _, coAuthor := range app.CoAuthors {
+			if coAuthor.UserRefer == int(user.Id) && coAuthor.State == schema.CoAuthorAccepted {
+				relations |= CoAuthor
+			}
+		}
+	}
+
+	switch user.Role {
+	case role.Admin:
+		relations |= Admin | Moderator
+	case role.Manager:
+		relations |= Moderator
+	}
+
+	return relations
+}
+
+func Can(user schema.User, action Action, app *schema.RulerApplication) bool {
+	return rules[action]&Relations(user, app) != 0
+}
+
+// Authorize returns ErrForbidden, naming the action, unless user may perform it.
+func Authorize(user schema.User, action Action, app *schema.RulerApplication) error {
+	if !Can(user, action, app) {
+		return fmt.Errorf("%w: %s", ErrForbidden, action)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"kingdoms/internal/database/schema"
+	role "kingdoms/internal/server/app/userRole"
+)
+
+var (
+	owner    = schema.User{Id: 1, Role: role.Buyer}
+	coAuthor = schema.User{Id: 2, Role: role.Buyer}
+	invitee  = schema.User{Id: 3, Role: role.Buyer}
+	stranger = schema.User{Id: 4, Role: role.Buyer}
+	manager  = schema.User{Id: 5, Role: role.Manager}
+	admin    = schema.User{Id: 6, Role: role.Admin}
+)
+
+func application() *schema.RulerApplication {
+	return &schema.RulerApplication{
+		Id:           10,
+		CreatorRefer: int(owner.Id),
+		CoAuthors: []schema.ApplicationCoAuthor{
+			{UserRefer: int(coAuthor.Id), State: schema.CoAuthorAccepted},
+			{UserRefer: int(invitee.Id), State: schema.CoAuthorPending},
+		},
+	}
+}
+
+func TestRelations(t *testing.T) {
+	managerOwned := application()
+	managerOwned.CreatorRefer = int(manager.Id)
+
+	tests := []struct {
+		name string
+		user schema.User
+		app  *schema.RulerApplication
+		want Relation
+	}{
+		{"owner", owner, application(), Owner},
+		{"accepted co-author", coAuthor, application(), CoAuthor},
+		{"pending invitee", invitee, application(), 0},
+		{"stranger", stranger, application(), 0},
+		{"manager", manager, application(), Moderator},
+		{"admin", admin, application(), Admin | Moderator},
+		{"manager who created it", manager, managerOwned, Owner | Moderator},
+		{"no application", owner, nil, 0},
+		{"admin without application", admin, nil, Admin | Moderator},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Relations(tt.user, tt.app); got != tt.want {
+				t.Errorf("Relations() = %b, want %b", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		user    schema.User
+		action  Action
+		allowed bool
+	}{
+		{owner, ReadApplication, true},
+		{owner, EditApplication, true},
+		{owner, SubmitApplication, true},
+		{owner, DeleteApplication, true},
+		{owner, CloneApplication, true},
+		{owner, RestoreApplication, true},
+		{owner, Discuss, true},
+		{owner, ManageCoAuthors, true},
+		{owner, DecideApplication, false},
+		{owner, ListApplications, false},
+
+		{coAuthor, ReadApplication, true},
+		{coAuthor, EditApplication, true},
+		{coAuthor, Discuss, true},
+		{coAuthor, SubmitApplication, false},
+		{coAuthor, DeleteApplication, false},
+		{coAuthor, ManageCoAuthors, false},
+		{coAuthor, CloneApplication, false},
+
+		{invitee, ReadApplication, false},
+		{invitee, EditApplication, false},
+
+		{stranger, ReadApplication, false},
+		{stranger, Discuss, false},
+
+		{manager, ReadApplication, true},
+		{manager, DecideApplication, true},
+		{manager, ModerateComments, true},
+		{manager, EditApplication, false},
+		{manager, DeleteApplication, false},
+		{manager, CloneApplication, false},
+		{manager, RestoreApplication, false},
+		{manager, ManageDrafts, false},
+
+		{admin, DeleteApplication, true},
+		{admin, RestoreApplication, true},
+		{admin, ManageDrafts, true},
+		{admin, EditApplication, false},
+		{admin, SubmitApplication, false},
+	}
+
+	for _, tt := range tests {
+		err := Authorize(tt.user, tt.action, application())
+		if tt.allowed && err != nil {
+			t.Errorf("user %d, %s: error %v, want allowed", tt.user.Id, tt.action, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrForbidden) {
+			t.Errorf("user %d, %s: error %v, want ErrForbidden", tt.user.Id, tt.action, err)
+		}
+	}
+}
+
+func TestUnknownActionIsForbidden(t *testing.T) {
+	if Can(admin, Action("drop database"), application()) {
+		t.Error("an action without a rule is allowed")
+	}
+}
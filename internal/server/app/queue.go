@@ -0,0 +1,281 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"kingdoms/internal/database/schema"
+	role "kingdoms/internal/server/app/userRole"
+	"kingdoms/internal/server/models/responseModels"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errApplicationLocked = errors.New("application is locked by another moderator")
+
+// holdApplicationLock takes moderator's lock on an application for a decision,
+// or extends it when the moderator took the application from the queue, and
+// refuses while another moderator holds it. Holding rather than only reading
+// the lock keeps anyone from taking the application mid-decision. It tells
+// whether the lock was taken just for the decision, for the caller to release.
+func (a *Application) holdApplicationLock(ctx *gin.Context, moderator schema.User, applicationId uint) (bool, error) {
+	locked, taken, err := a.redis.LockApplication(ctx, applicationId, moderator.Name, a.config.Queue.LockTTL)
+	if err != nil {
+		return false, err
+	}
+
+	if !locked {
+		holder, err := a.redis.ApplicationLockHolder(ctx, applicationId)
+		if err != nil {
+			return false, err
+		}
+
+		return false, fmt.Errorf("%w: %s", errApplicationLocked, holder)
+	}
+
+	return taken, nil
+}
+
+func (a *Application) takeNextApplication(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	haveRights, response := checkUserRights(*user)
+	if !haveRights {
+		ctx.JSON(http.StatusForbidden, response)
+		return
+	}
+
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	ids, err := a.repo.GetQueuedApplicationIds(*user)
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error getting application queue: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	for _, id := range ids {
+		locked, _, err := a.redis.LockApplication(ctx, id, user.Name, a.config.Queue.LockTTL)
+		if err != nil {
+			response := responseModels.ResponseDefault{
+				Code:    500,
+				Status:  "error",
+				Message: "error locking application: " + err.Error(),
+				Body:    nil,
+			}
+
+			ctx.JSON(http.StatusInternalServerError, response)
+			return
+		}
+
+		if !locked {
+			continue
+		}
+
+		application, err := a.repo.GetApplicationWithKingdoms(*user, strconv.FormatUint(uint64(id), 10))
+		if err != nil {
+			_, _ = a.redis.UnlockApplication(ctx, id, user.Name)
+
+			response := responseModels.ResponseDefault{
+				Code:    500,
+				Status:  "error",
+				Message: "error getting application: " + err.Error(),
+				Body:    nil,
+			}
+
+			ctx.JSON(http.StatusInternalServerError, response)
+			return
+		}
+
+		response = responseModels.ResponseDefault{
+			Code:    200,
+			Status:  "ok",
+			Message: "application taken from queue",
+			Body:    inZone(application, zone),
+		}
+
+		ctx.JSON(http.StatusOK, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    404,
+		Status:  "error",
+		Message: "no unclaimed applications in queue",
+		Body:    nil,
+	}
+
+	ctx.JSON(http.StatusNotFound, response)
+}
+
+func (a *Application) releaseApplication(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	haveRights, response := checkUserRights(*user)
+	if !haveRights {
+		ctx.JSON(http.StatusForbidden, response)
+		return
+	}
+
+	applicationId, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing application id",
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// admins may free applications left locked by someone else
+	if user.Role == role.Admin {
+		err = a.redis.ForceUnlockApplication(ctx, uint(applicationId))
+	} else {
+		var holder string
+		holder, err = a.redis.UnlockApplication(ctx, uint(applicationId), user.Name)
+		if err == nil && holder != "" {
+			err = fmt.Errorf("%w: %s", errApplicationLocked, holder)
+		}
+	}
+
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error releasing application: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "application released",
+		Body:    nil,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (a *Application) getApplicationLocks(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	haveRights, response := checkUserRights(*user)
+	if !haveRights {
+		ctx.JSON(http.StatusForbidden, response)
+		return
+	}
+
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	locks, err := a.redis.ApplicationLocks(ctx)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting application locks: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "application locks found",
+		Body:    inZone(locks, zone),
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,233 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/models/responseModels"
+	"kingdoms/internal/server/processing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const timeZoneHeader = "X-Timezone"
+
+// requestZone picks the zone dates are read and rendered in: the X-Timezone
+// header, then the TZ query parameter, then the user's saved preference, UTC
+// otherwise.
+func requestZone(ctx *gin.Context, user schema.User) (*time.Location, error) {
+	name := ctx.GetHeader(timeZoneHeader)
+	if name == "" {
+		name = ctx.Query("TZ")
+	}
+	if name == "" {
+		name = user.TimeZone
+	}
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+
+	return loc, nil
+}
+
+// parseQueryTime accepts an RFC 3339 date-time, a date-time without offset or
+// a plain date. Values without an offset are read in loc; a plain date used as
+// an upper bound covers the whole day.
+func parseQueryTime(str string, loc *time.Location, isEnd bool) (time.Time, error) {
+	value, err := time.Parse(time.RFC3339Nano, str)
+	if err == nil {
+		return value.UTC(), nil
+	}
+
+	if strings.Contains(str, "T") {
+		value, err = time.ParseInLocation("2006-01-02T15:04:05", str, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("must be RFC 3339 date-time like 2006-01-02T15:04:05+03:00")
+		}
+
+		return value.UTC(), nil
+	}
+
+	value, err = time.ParseInLocation("2006-01-02", str, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be a date like 2006-01-02 or RFC 3339 date-time")
+	}
+
+	if isEnd {
+		value = value.AddDate(0, 0, 1)
+	}
+
+	return value.UTC(), nil
+}
+
+func timeInZone(t time.Time, loc *time.Location) time.Time {
+	// unset dates stay zero instead of turning into local mean time of year 1
+	if t.IsZero() {
+		return t
+	}
+
+	return t.In(loc)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// inZone returns a copy of body with every time.Time in it, however deeply
+// nested, rendered in loc. Kingdom periods are datatypes.Date and stay as
+// they are: a date reads the same in every zone.
+func inZone(body interface{}, loc *time.Location) interface{} {
+	if body == nil {
+		return nil
+	}
+
+	return valueInZone(reflect.ValueOf(body), loc).Interface()
+}
+
+func valueInZone(v reflect.Value, loc *time.Location) reflect.Value {
+	if v.Type() == timeType {
+		return reflect.ValueOf(timeInZone(v.Interface().(time.Time), loc))
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(valueInZone(v.Elem(), loc))
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(valueInZone(v.Elem(), loc))
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			// unexported fields are not rendered, and cannot be set
+			if copied.Field(i).CanSet() {
+				copied.Field(i).Set(valueInZone(v.Field(i), loc))
+			}
+		}
+		return copied
+	case reflect.Slice:
+		if v.IsNil() || plainKind(v.Type().Elem().Kind()) {
+			return v
+		}
+
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(valueInZone(v.Index(i), loc))
+		}
+		return copied
+	case reflect.Array:
+		copied := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(valueInZone(v.Index(i), loc))
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), valueInZone(iter.Value(), loc))
+		}
+		return copied
+	}
+
+	return v
+}
+
+// plainKind tells whether values of the kind hold no time, e.g. the bytes of
+// a JSON column.
+func plainKind(kind reflect.Kind) bool {
+	return kind <= reflect.Complex128 || kind == reflect.String
+}
+
+func (a *Application) updateUserTimeZone(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	var timeZone processing.TimeZoneToUpdate
+	if err := ctx.BindJSON(&timeZone); err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone:" + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// an empty zone clears the preference
+	if timeZone.TimeZone != "" {
+		if _, err := time.LoadLocation(timeZone.TimeZone); err != nil {
+			response := responseModels.ResponseDefault{
+				Code:    400,
+				Status:  "error",
+				Message: "error parsing time zone: unknown time zone " + timeZone.TimeZone,
+				Body:    nil,
+			}
+
+			ctx.JSON(http.StatusBadRequest, response)
+			return
+		}
+	}
+
+	err = a.repo.UpdateUserTimeZone(*user, timeZone.TimeZone)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error updating time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "time zone updated successfully",
+		Body:    nil,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/processing"
+
+	"gorm.io/datatypes"
+)
+
+func TestInZone(t *testing.T) {
+	novosibirsk, err := time.LoadLocation("Asia/Novosibirsk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2024, 3, 1, 20, 30, 0, 0, time.UTC)
+	revoked := time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)
+	period := datatypes.Date(time.Date(1132, 4, 15, 0, 0, 0, 0, time.UTC))
+
+	comments := []schema.ApplicationComment{{Id: 1, DateCreate: created, DateUpdate: &revoked}}
+	verification := processing.CharterVerification{
+		Valid:        true,
+		Kingdoms:     []processing.CharterKingdom{{Name: "Киевское", From: period, To: period}},
+		DecisionDate: &created,
+		DateRevoked:  &revoked,
+	}
+	body := map[string]interface{}{"Comments": comments, "Empty": nil}
+
+	gotComments := inZone(comments, novosibirsk).([]schema.ApplicationComment)
+	if gotComments[0].DateCreate.Location() != novosibirsk || gotComments[0].DateCreate.Hour() != 3 {
+		t.Errorf("DateCreate = %v, want 03:30 in Novosibirsk", gotComments[0].DateCreate)
+	}
+	if gotComments[0].DateUpdate.Location() != novosibirsk {
+		t.Errorf("DateUpdate = %v, want it in Novosibirsk", *gotComments[0].DateUpdate)
+	}
+	if !gotComments[0].DateCreate.Equal(created) {
+		t.Errorf("DateCreate = %v, want the same instant as %v", gotComments[0].DateCreate, created)
+	}
+
+	// the original is left as it was
+	if comments[0].DateCreate.Location() != time.UTC || comments[0].DateUpdate.Location() != time.UTC {
+		t.Errorf("inZone changed its argument: %v", comments[0])
+	}
+
+	gotVerification := inZone(verification, novosibirsk).(processing.CharterVerification)
+	if gotVerification.DecisionDate.Location() != novosibirsk || gotVerification.DateRevoked.Location() != novosibirsk {
+		t.Errorf("verification dates = %v, %v, want them in Novosibirsk",
+			*gotVerification.DecisionDate, *gotVerification.DateRevoked)
+	}
+	if time.Time(gotVerification.Kingdoms[0].From) != time.Time(period) {
+		t.Errorf("period From = %v, want the date unchanged", time.Time(gotVerification.Kingdoms[0].From))
+	}
+
+	gotBody := inZone(body, novosibirsk).(map[string]interface{})
+	if gotBody["Comments"].([]schema.ApplicationComment)[0].DateCreate.Location() != novosibirsk {
+		t.Errorf("nested DateCreate not converted: %v", gotBody["Comments"])
+	}
+	if gotBody["Empty"] != nil {
+		t.Errorf("Empty = %v, want nil", gotBody["Empty"])
+	}
+
+	zero := inZone(schema.ApplicationComment{}, novosibirsk).(schema.ApplicationComment)
+	if !zero.DateCreate.IsZero() || zero.DateUpdate != nil {
+		t.Errorf("unset dates = %v, %v, want them unset", zero.DateCreate, zero.DateUpdate)
+	}
+
+	if inZone(nil, novosibirsk) != nil {
+		t.Error("inZone(nil) is not nil")
+	}
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/models/responseModels"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runTrashPurge periodically deletes for good the applications whose trash
+// retention has expired.
+func (a *Application) runTrashPurge() {
+	if a.config.Trash.PurgeInterval <= 0 {
+		log.Println("trash purge disabled")
+		return
+	}
+
+	ticker := time.NewTicker(a.config.Trash.PurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := a.repo.PurgeTrash()
+		if err != nil {
+			log.Println("trash purge failed:", err)
+			continue
+		}
+
+		for _, entry := range purged {
+			log.Printf("trash purge: application %d of user %d deleted at %s removed",
+				entry.ApplicationRefer, entry.CreatorRefer, entry.DateDelete.Format(time.RFC3339))
+		}
+	}
+}
+
+func (a *Application) getTrash(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	applications, err := a.repo.GetTrash(*user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting deleted applications: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: "deleted applications found",
+		Body:    inZone(applications, zone),
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (a *Application) restoreApplication(ctx *gin.Context) {
+	myClaims, response := a.repo.FoundUserFromHeader(ctx, a.redis, a.config)
+	if response != (responseModels.ResponseDefault{}) {
+		ctx.JSON(response.Code, response)
+		return
+	}
+
+	user, err := a.repo.GetUserByName(myClaims.Name)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    500,
+			Status:  "error",
+			Message: "error getting user by name: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	zone, err := requestZone(ctx, *user)
+	if err != nil {
+		response := responseModels.ResponseDefault{
+			Code:    400,
+			Status:  "error",
+			Message: "error parsing time zone: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	application, err := a.repo.RestoreApplication(*user, ctx.Param("id"))
+	if err != nil {
+		code := applicationErrorCode(err)
+		response := responseModels.ResponseDefault{
+			Code:    code,
+			Status:  "error",
+			Message: "error restoring application: " + err.Error(),
+			Body:    nil,
+		}
+
+		ctx.JSON(code, response)
+		return
+	}
+
+	// restoring always gives a draft; say so when it was deleted from another state
+	message := "application restored successfully"
+	if application.PreviousState != "" && application.PreviousState != appState.Draft {
+		message = "application restored as a draft: it was " + string(application.PreviousState) +
+			" when deleted and has to be submitted again"
+	}
+
+	response = responseModels.ResponseDefault{
+		Code:    200,
+		Status:  "ok",
+		Message: message,
+		Body:    inZone(application, zone),
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
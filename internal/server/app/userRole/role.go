@@ -1,5 +1,7 @@
 package role
 
+import "fmt"
+
 type Role int
 
 const (
@@ -8,3 +10,28 @@ const (
 	Manager
 	Admin
 )
+
+var names = map[Role]string{
+	Buyer:   "Buyer",
+	Manager: "Manager",
+	Admin:   "Admin",
+}
+
+func (r Role) String() string {
+	if name, ok := names[r]; ok {
+		return name
+	}
+
+	return "Unknown"
+}
+
+// Parse returns the role named name, as written in the config.
+func Parse(name string) (Role, error) {
+	for r, roleName := range names {
+		if roleName == name {
+			return r, nil
+		}
+	}
+
+	return Unknown, fmt.Errorf("unknown role %q", name)
+}
@@ -0,0 +1,212 @@
+package processing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/app/policy"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultApplicationsLimit = 50
+	MaxApplicationsLimit     = 200
+)
+
+var ErrInvalidQuery = errors.New("invalid application query")
+
+// applicationSortColumns lists the columns the application list may be sorted
+// by. Every sort falls back to id, so keyset pages never skip or repeat rows.
+var applicationSortColumns = map[string]string{
+	"id":            "id",
+	"date_create":   "date_create",
+	"date_send":     "date_send",
+	"date_complete": "date_complete",
+	"ruler":         "ruler",
+}
+
+// applicationCursor points just past the last application of a page: the value
+// of its sort column and its id.
+type applicationCursor struct {
+	Value string `json:"v,omitempty"`
+	Id    uint   `json:"id"`
+}
+
+func encodeApplicationCursor(sort string, app schema.RulerApplication) string {
+	cursor := applicationCursor{Id: app.Id}
+
+	switch sort {
+	case "date_create":
+		cursor.Value = app.DateCreate.Format(time.RFC3339Nano)
+	case "date_send":
+		cursor.Value = app.DateSend.Format(time.RFC3339Nano)
+	case "date_complete":
+		cursor.Value = app.DateComplete.Format(time.RFC3339Nano)
+	case "ruler":
+		cursor.Value = app.Ruler
+	}
+
+	data, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeApplicationCursor returns the cursor id and the sort value typed for
+// the column, so the database compares it as a timestamp rather than text.
+func decodeApplicationCursor(sort string, str string) (interface{}, uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	var cursor applicationCursor
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	switch sort {
+	case "id":
+		return cursor.Id, cursor.Id, nil
+	case "ruler":
+		return cursor.Value, cursor.Id, nil
+	}
+
+	value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: cursor does not match sort %s", ErrInvalidQuery, sort)
+	}
+
+	return value, cursor.Id, nil
+}
+
+// likeEscaper escapes the LIKE wildcards, and the escape character itself, so
+// user input is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern is the LIKE pattern matching values that contain str.
+func containsPattern(str string) string {
+	return "%" + likeEscaper.Replace(str) + "%"
+}
+
+// applicationsQuery applies the filters of params to the application list.
+func applicationsQuery(tx *gorm.DB, params StructGetAllApplications) *gorm.DB {
+	query := tx.Model(&schema.RulerApplication{})
+
+	if len(params.States) > 0 {
+		query = query.Where("state IN ?", params.States)
+	} else {
+		query = query.Where("state NOT IN ?", []appState.State{appState.Deleted, appState.Draft})
+	}
+
+	if params.Creator != 0 {
+		query = query.Where("creator_refer = ?", params.Creator)
+	}
+	if params.Moderator != 0 {
+		query = query.Where("moderator_refer = ?", params.Moderator)
+	}
+	if params.Kingdom != 0 {
+		query = query.Where(`EXISTS (SELECT 1 FROM kingdom2_applications
+			WHERE kingdom2_applications.application_refer = ruler_applications.id
+			AND kingdom2_applications.kingdom_refer = ?)`, params.Kingdom)
+	}
+	if params.Check != nil {
+		query = query.Where(`"check" = ?`, *params.Check)
+	}
+	if params.Ruler != "" {
+		query = query.Where(`ruler ILIKE ? ESCAPE '\'`, containsPattern(params.Ruler))
+	}
+
+	ranges := []struct {
+		column   string
+		from, to time.Time
+	}{
+		{"date_create", params.CreatedFrom, params.CreatedTo},
+		{"date_send", params.SentFrom, params.SentTo},
+		{"date_complete", params.CompletedFrom, params.CompletedTo},
+	}
+	for _, r := range ranges {
+		if !r.from.IsZero() {
+			query = query.Where(r.column+" >= ?", r.from)
+		}
+		if !r.to.IsZero() {
+			query = query.Where(r.column+" < ?", r.to)
+		}
+	}
+
+	return query
+}
+
+// GetAllApplications returns one page of the applications matching params.
+// NextCursor is empty on the last page.
+func (r *Repository) GetAllApplications(user schema.User, params StructGetAllApplications) (ApplicationsPage, error) {
+	err := policy.Authorize(user, policy.ListApplications, nil)
+	if err != nil {
+		return ApplicationsPage{}, err
+	}
+
+	if params.Sort == "" {
+		params.Sort = "id"
+	}
+	column, ok := applicationSortColumns[params.Sort]
+	if !ok {
+		return ApplicationsPage{}, fmt.Errorf("%w: unknown sort %s", ErrInvalidQuery, params.Sort)
+	}
+
+	if params.Limit <= 0 {
+		params.Limit = DefaultApplicationsLimit
+	}
+	if params.Limit > MaxApplicationsLimit {
+		params.Limit = MaxApplicationsLimit
+	}
+
+	direction, compare := "ASC", ">"
+	if params.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	query := applicationsQuery(r.db, params)
+
+	if params.Cursor != "" {
+		value, id, err := decodeApplicationCursor(params.Sort, params.Cursor)
+		if err != nil {
+			return ApplicationsPage{}, err
+		}
+
+		if column == "id" {
+			query = query.Where("id "+compare+" ?", id)
+		} else {
+			query = query.Where("("+column+", id) "+compare+" (?, ?)", value, id)
+		}
+	}
+
+	if column != "id" {
+		query = query.Order(column + " " + direction)
+	}
+
+	applications := []schema.RulerApplication{}
+	err = query.
+		Order("id " + direction).
+		Limit(params.Limit + 1).
+		Preload("Creator").
+		Preload("Moderator").
+		Find(&applications).Error
+	if err != nil {
+		return ApplicationsPage{}, err
+	}
+
+	page := ApplicationsPage{Applications: applications}
+	if len(applications) > params.Limit {
+		page.Applications = applications[:params.Limit]
+		page.NextCursor = encodeApplicationCursor(params.Sort, page.Applications[params.Limit-1])
+	}
+
+	return page, nil
+}
@@ -0,0 +1,23 @@
+package processing
+
+import "testing"
+
+func TestContainsPattern(t *testing.T) {
+	tests := []struct {
+		str  string
+		want string
+	}{
+		{"Ярослав", "%Ярослав%"},
+		{"", "%%"},
+		{"_", `%\_%`},
+		{"100%", `%100\%%`},
+		{`a\b`, `%a\\b%`},
+		{`\_%`, `%\\\_\%%`},
+	}
+
+	for _, tt := range tests {
+		if got := containsPattern(tt.str); got != tt.want {
+			t.Errorf("containsPattern(%q) = %q, want %q", tt.str, got, tt.want)
+		}
+	}
+}
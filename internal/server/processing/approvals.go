@@ -0,0 +1,230 @@
+package processing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"kingdoms/internal/config"
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	role "kingdoms/internal/server/app/userRole"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAlreadySigned = errors.New("a moderator signs off at most one stage of an application")
+	ErrStageRole     = errors.New("approval stage needs a higher role")
+)
+
+type approvalStage struct {
+	Name string
+	Role role.Role
+}
+
+type approvalChain struct {
+	Name        string
+	MinKingdoms int
+	MinArea     int
+	Stages      []approvalStage
+}
+
+// defaultChain applies when no configured chain matches: one moderator
+// approves, as before chains existed.
+var defaultChain = approvalChain{
+	Name:   "default",
+	Stages: []approvalStage{{Name: "approval", Role: role.Manager}},
+}
+
+// approvalChains checks the configured chains, so a mistake in the config
+// stops the server at start rather than blocking approvals later. Configuring
+// none is always valid: every application then takes the default chain.
+func approvalChains(cfg config.ApprovalsConfig) ([]approvalChain, error) {
+	if len(cfg.Chains) == 0 {
+		return nil, nil
+	}
+
+	chains := make([]approvalChain, 0, len(cfg.Chains))
+	for _, chainConfig := range cfg.Chains {
+		if len(chainConfig.Stages) == 0 {
+			return nil, fmt.Errorf("approval chain %q has no stages", chainConfig.Name)
+		}
+
+		chain := approvalChain{
+			Name:        chainConfig.Name,
+			MinKingdoms: chainConfig.MinKingdoms,
+			MinArea:     chainConfig.MinArea,
+		}
+		for _, stageConfig := range chainConfig.Stages {
+			stageRole, err := role.Parse(stageConfig.Role)
+			if err != nil {
+				return nil, fmt.Errorf("approval chain %q, stage %q: %w", chainConfig.Name, stageConfig.Name, err)
+			}
+			if stageRole != role.Manager && stageRole != role.Admin {
+				return nil, fmt.Errorf("approval chain %q, stage %q: only moderators approve",
+					chainConfig.Name, stageConfig.Name)
+			}
+
+			chain.Stages = append(chain.Stages, approvalStage{Name: stageConfig.Name, Role: stageRole})
+		}
+
+		chains = append(chains, chain)
+	}
+
+	return chains, nil
+}
+
+func (c approvalChain) matches(kingdoms int, area int) bool {
+	if c.MinKingdoms == 0 && c.MinArea == 0 {
+		return true
+	}
+
+	return (c.MinKingdoms > 0 && kingdoms >= c.MinKingdoms) || (c.MinArea > 0 && area >= c.MinArea)
+}
+
+// chainFor picks the chain for the application by the kingdoms it still
+// claims: lines a moderator rejected do not count.
+func (r *Repository) chainFor(tx *gorm.DB, app schema.RulerApplication) (approvalChain, error) {
+	var claimed struct {
+		Kingdoms int
+		Area     int
+	}
+	err := tx.Table("kingdom2_applications").
+		Select("COUNT(*) AS kingdoms, COALESCE(SUM(kingdoms.area), 0) AS area").
+		Joins("JOIN kingdoms ON kingdoms.id = kingdom2_applications.kingdom_refer").
+		Where("kingdom2_applications.application_refer = ? AND kingdom2_applications.decision != ?",
+			app.Id, LineRejected).
+		Scan(&claimed).Error
+	if err != nil {
+		return approvalChain{}, err
+	}
+
+	for _, chain := range r.chains {
+		if chain.matches(claimed.Kingdoms, claimed.Area) {
+			return chain, nil
+		}
+	}
+
+	return defaultChain, nil
+}
+
+func activeApprovals(tx *gorm.DB, applicationId uint) ([]schema.ApplicationApproval, error) {
+	var approvals []schema.ApplicationApproval
+	err := tx.Where("application_refer = ? AND date_revoked IS NULL", applicationId).
+		Order("stage, id").
+		Preload("Approver", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, uuid, name, role")
+		}).
+		Find(&approvals).Error
+	if err != nil {
+		return []schema.ApplicationApproval{}, err
+	}
+
+	return approvals, nil
+}
+
+// signApproval signs off the next stage of the application's chain on behalf
+// of user and tells whether the chain is now complete. A chain signed off in
+// full already gets no further approval, but the one completing it is checked
+// all the same. Callers hold the lock on the application row.
+func (r *Repository) signApproval(tx *gorm.DB, user schema.User, app schema.RulerApplication,
+	comment string) (schema.ApplicationApproval, bool, error) {
+
+	chain, err := r.chainFor(tx, app)
+	if err != nil {
+		return schema.ApplicationApproval{}, false, err
+	}
+
+	signed, err := activeApprovals(tx, app.Id)
+	if err != nil {
+		return schema.ApplicationApproval{}, false, err
+	}
+
+	for _, approval := range signed {
+		if approval.ApproverRefer == int(user.Id) {
+			return schema.ApplicationApproval{}, false, ErrAlreadySigned
+		}
+	}
+
+	// the chain becomes shorter when lines are rejected, and may already be
+	// signed off in full: the approval then completes it, but only from
+	// someone entitled to sign its last stage
+	if len(signed) >= len(chain.Stages) {
+		last := chain.Stages[len(chain.Stages)-1]
+		if user.Role < last.Role {
+			return schema.ApplicationApproval{}, false, fmt.Errorf("%w: %s needs %s", ErrStageRole, last.Name, last.Role)
+		}
+
+		return schema.ApplicationApproval{}, true, nil
+	}
+
+	stage := chain.Stages[len(signed)]
+
+	if user.Role < stage.Role {
+		return schema.ApplicationApproval{}, false, fmt.Errorf("%w: %s needs %s", ErrStageRole, stage.Name, stage.Role)
+	}
+
+	approval := schema.ApplicationApproval{
+		ApplicationRefer: int(app.Id),
+		Stage:            len(signed),
+		StageName:        stage.Name,
+		ApproverRefer:    int(user.Id),
+		Comment:          comment,
+	}
+	err = tx.Create(&approval).Error
+	if err != nil {
+		return schema.ApplicationApproval{}, false, err
+	}
+
+	return approval, len(signed)+1 >= len(chain.Stages), nil
+}
+
+// revokeApprovals voids the signatures collected so far, so the chain starts
+// over the next time the application is up for a decision.
+func revokeApprovals(tx *gorm.DB, applicationId uint) error {
+	return tx.Model(&schema.ApplicationApproval{}).
+		Where("application_refer = ? AND date_revoked IS NULL", applicationId).
+		Update("date_revoked", time.Now().UTC()).Error
+}
+
+// GetApplicationApprovals shows which stages of the application's chain have
+// been signed off and by whom.
+func (r *Repository) GetApplicationApprovals(user schema.User, applicationId string) (ApprovalProgress, error) {
+	var tx *gorm.DB = r.db
+
+	app, err := readableApplication(tx, user, applicationId)
+	if err != nil {
+		return ApprovalProgress{}, err
+	}
+
+	chain, err := r.chainFor(tx, app)
+	if err != nil {
+		return ApprovalProgress{}, err
+	}
+
+	signed, err := activeApprovals(tx, app.Id)
+	if err != nil {
+		return ApprovalProgress{}, err
+	}
+
+	progress := ApprovalProgress{
+		Chain:    chain.Name,
+		Complete: app.State == appState.Approved,
+		Stages:   []StageProgress{},
+	}
+	for i, stage := range chain.Stages {
+		progress.Stages = append(progress.Stages, StageProgress{
+			Stage: i,
+			Name:  stage.Name,
+			Role:  stage.Role.String(),
+		})
+	}
+	for i := range signed {
+		if signed[i].Stage < len(progress.Stages) {
+			progress.Stages[signed[i].Stage].Approval = &signed[i]
+		}
+	}
+
+	return progress, nil
+}
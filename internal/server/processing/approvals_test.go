@@ -0,0 +1,286 @@
+package processing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"kingdoms/internal/config"
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	role "kingdoms/internal/server/app/userRole"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+func TestApprovalChainMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		chain    approvalChain
+		kingdoms int
+		area     int
+		want     bool
+	}{
+		{"no thresholds matches everything", approvalChain{}, 0, 0, true},
+		{"enough kingdoms", approvalChain{MinKingdoms: 5}, 5, 0, true},
+		{"too few kingdoms", approvalChain{MinKingdoms: 5}, 4, 1000000, false},
+		{"enough area", approvalChain{MinArea: 500000}, 1, 500000, true},
+		{"too little area", approvalChain{MinArea: 500000}, 100, 499999, false},
+		{"either threshold, kingdoms", approvalChain{MinKingdoms: 5, MinArea: 500000}, 5, 0, true},
+		{"either threshold, area", approvalChain{MinKingdoms: 5, MinArea: 500000}, 1, 600000, true},
+		{"neither threshold", approvalChain{MinKingdoms: 5, MinArea: 500000}, 4, 499999, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.chain.matches(tt.kingdoms, tt.area); got != tt.want {
+				t.Errorf("matches(%d, %d) = %v, want %v", tt.kingdoms, tt.area, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApprovalChains(t *testing.T) {
+	stage := func(name string, stageRole string) config.ApprovalStageConfig {
+		return config.ApprovalStageConfig{Name: name, Role: stageRole}
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.ApprovalsConfig
+		want    []approvalChain
+		wantErr string
+	}{
+		{
+			name: "nothing configured",
+			cfg:  config.ApprovalsConfig{},
+			want: nil,
+		},
+		{
+			name: "valid chains",
+			cfg: config.ApprovalsConfig{Chains: []config.ApprovalChainConfig{
+				{
+					Name:        "large",
+					MinKingdoms: 5,
+					Stages:      []config.ApprovalStageConfig{stage("review", "Manager"), stage("final", "Admin")},
+				},
+				{Name: "any", Stages: []config.ApprovalStageConfig{stage("review", "Manager")}},
+			}},
+			want: []approvalChain{
+				{
+					Name:        "large",
+					MinKingdoms: 5,
+					Stages:      []approvalStage{{Name: "review", Role: role.Manager}, {Name: "final", Role: role.Admin}},
+				},
+				{Name: "any", Stages: []approvalStage{{Name: "review", Role: role.Manager}}},
+			},
+		},
+		{
+			name: "chain without stages",
+			cfg: config.ApprovalsConfig{Chains: []config.ApprovalChainConfig{
+				{Name: "empty"},
+			}},
+			wantErr: `"empty" has no stages`,
+		},
+		{
+			name: "unknown role",
+			cfg: config.ApprovalsConfig{Chains: []config.ApprovalChainConfig{
+				{Name: "typo", Stages: []config.ApprovalStageConfig{stage("review", "Manger")}},
+			}},
+			wantErr: `unknown role "Manger"`,
+		},
+		{
+			name: "buyer cannot approve",
+			cfg: config.ApprovalsConfig{Chains: []config.ApprovalChainConfig{
+				{Name: "buyers", Stages: []config.ApprovalStageConfig{stage("review", "Buyer")}},
+			}},
+			wantErr: "only moderators approve",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := approvalChains(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("approvalChains() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("approvalChains() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Name != tt.want[i].Name || got[i].MinKingdoms != tt.want[i].MinKingdoms ||
+					got[i].MinArea != tt.want[i].MinArea || len(got[i].Stages) != len(tt.want[i].Stages) {
+					t.Fatalf("chain %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+				for j := range got[i].Stages {
+					if got[i].Stages[j] != tt.want[i].Stages[j] {
+						t.Errorf("chain %d stage %d = %+v, want %+v", i, j, got[i].Stages[j], tt.want[i].Stages[j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultChain(t *testing.T) {
+	if len(defaultChain.Stages) != 1 || defaultChain.Stages[0].Role != role.Manager {
+		t.Errorf("defaultChain = %+v, want a single Manager stage", defaultChain)
+	}
+	if !defaultChain.matches(0, 0) {
+		t.Error("defaultChain does not match every application")
+	}
+}
+
+// TestShippedApprovalChains keeps the chains in the shipped config valid, so
+// the server starts with it.
+func TestShippedApprovalChains(t *testing.T) {
+	v := viper.New()
+	v.SetConfigFile("../../config/config.toml")
+	err := v.ReadInConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg config.ApprovalsConfig
+	err = v.UnmarshalKey("Approvals", &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chains, err := approvalChains(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != len(cfg.Chains) {
+		t.Errorf("approvalChains() = %d chains, want %d", len(chains), len(cfg.Chains))
+	}
+}
+
+// councilChains send applications of two kingdoms or more through a review and
+// a council stage, and the others through a single stage only admins sign.
+var councilChains = []approvalChain{
+	{
+		Name:        "council",
+		MinKingdoms: 2,
+		Stages:      []approvalStage{{Name: "review", Role: role.Manager}, {Name: "council", Role: role.Manager}},
+	},
+	{Name: "small", Stages: []approvalStage{{Name: "final", Role: role.Admin}}},
+}
+
+func TestSignApprovalShrunkChain(t *testing.T) {
+	r := testRepository(t, councilChains...)
+	owner := testUser(t, r, "owner", role.Buyer)
+	reviewer := testUser(t, r, "reviewer", role.Manager)
+	other := testUser(t, r, "other", role.Manager)
+	admin := testUser(t, r, "admin", role.Admin)
+	app := testApplication(t, r, owner, appState.Submitted,
+		testKingdom(t, r, "Киевское", 100), testKingdom(t, r, "Черниговское", 100))
+
+	approval, final, err := r.signApproval(r.db, reviewer, app, "")
+	if err != nil || final || approval.StageName != "review" {
+		t.Fatalf("signApproval() = %+v, %v, %v, want the review stage signed", approval, final, err)
+	}
+
+	// rejecting a line leaves one kingdom: the chain is now "small", whose
+	// single stage is already covered by the review
+	err = r.db.Model(&schema.Kingdom2Application{}).
+		Where("application_refer = ? AND kingdom_refer = (SELECT id FROM kingdoms WHERE name = ?)", app.Id, "Черниговское").
+		Update("decision", LineRejected).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = r.signApproval(r.db, reviewer, app, "")
+	if !errors.Is(err, ErrAlreadySigned) {
+		t.Errorf("signer of the review finalizes: error = %v, want ErrAlreadySigned", err)
+	}
+
+	_, _, err = r.signApproval(r.db, other, app, "")
+	if !errors.Is(err, ErrStageRole) {
+		t.Errorf("manager finalizes an admin stage: error = %v, want ErrStageRole", err)
+	}
+
+	approval, final, err = r.signApproval(r.db, admin, app, "")
+	if err != nil || !final {
+		t.Fatalf("admin finalizes: final = %v, error = %v, want the chain complete", final, err)
+	}
+	if approval.Id != 0 {
+		t.Errorf("completing a signed off chain stored approval %+v", approval)
+	}
+
+	signed, err := activeApprovals(r.db, app.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signed) != 1 {
+		t.Errorf("%d approvals stored, want only the review", len(signed))
+	}
+}
+
+func TestApplyModeratorDecisionWithChain(t *testing.T) {
+	r := testRepository(t, councilChains...)
+	owner := testUser(t, r, "owner", role.Buyer)
+	reviewer := testUser(t, r, "reviewer", role.Manager)
+	council := testUser(t, r, "council", role.Manager)
+	app := testApplication(t, r, owner, appState.Submitted,
+		testKingdom(t, r, "Киевское", 100), testKingdom(t, r, "Черниговское", 100))
+
+	decide := func(user schema.User, target appState.State) error {
+		return r.db.Transaction(func(tx *gorm.DB) error {
+			return r.applyModeratorDecision(tx, user, app.Id, target, "")
+		})
+	}
+
+	err := decide(reviewer, appState.Approved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := applicationState(t, r, app.Id); state != appState.Submitted {
+		t.Fatalf("after the first stage the application is %s, want it still submitted", state)
+	}
+
+	err = decide(reviewer, appState.Approved)
+	if !errors.Is(err, ErrAlreadySigned) {
+		t.Errorf("second approval by the same moderator: error = %v, want ErrAlreadySigned", err)
+	}
+
+	// a rejection voids the review: the chain starts over
+	err = decide(council, appState.Rejected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = decide(council, appState.Submitted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := activeApprovals(r.db, app.Id)
+	if err != nil || len(signed) != 0 {
+		t.Fatalf("after rejection %d approvals remain (%v), want none", len(signed), err)
+	}
+
+	err = decide(council, appState.Approved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = decide(reviewer, appState.Approved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := applicationState(t, r, app.Id); state != appState.Approved {
+		t.Fatalf("after both stages the application is %s, want approved", state)
+	}
+
+	var charters int64
+	err = r.db.Model(&schema.Charter{}).Where("application_refer = ?", app.Id).Count(&charters).Error
+	if err != nil || charters != 1 {
+		t.Errorf("%d charters issued (%v), want one", charters, err)
+	}
+}
@@ -0,0 +1,264 @@
+package processing
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/policy"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxAttachmentNameLength = 255
+
+var (
+	ErrAttachmentTooLarge = errors.New("attachment is too large")
+	ErrAttachmentType     = errors.New("attachment type is not allowed")
+	ErrEmptyAttachment    = errors.New("attachment is empty")
+)
+
+// attachmentName keeps only the base name the client sent, falling back to a
+// name made from the detected type.
+func attachmentName(name string, detected *mimetype.MIME) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		name = "attachment" + detected.Extension()
+	}
+
+	runes := []rune(name)
+	if len(runes) > maxAttachmentNameLength {
+		name = string(runes[:maxAttachmentNameLength])
+	}
+
+	return name
+}
+
+func (r *Repository) allowedAttachmentType(detected *mimetype.MIME) bool {
+	for _, allowed := range r.cfg.Attachments.AllowedTypes {
+		if detected.Is(allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readableApplication returns the application if user may read it.
+func readableApplication(tx *gorm.DB, user schema.User, applicationId string) (schema.RulerApplication, error) {
+	var app schema.RulerApplication
+	err := tx.Where("id = ?", applicationId).First(&app).Error
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	err = loadCoAuthors(tx, &app)
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	err = policy.Authorize(user, policy.ReadApplication, &app)
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	return app, nil
+}
+
+// AddAttachment stores an uploaded file for a draft. The type is detected from
+// the content, not taken from the client.
+func (r *Repository) AddAttachment(user schema.User, applicationId string,
+	upload AttachmentUpload) (schema.Attachment, error) {
+
+	maxSize := r.cfg.Attachments.MaxSize
+	data, err := io.ReadAll(io.LimitReader(upload.Content, maxSize+1))
+	if err != nil {
+		return schema.Attachment{}, err
+	}
+	if int64(len(data)) > maxSize {
+		return schema.Attachment{}, fmt.Errorf("%w: more than %d bytes", ErrAttachmentTooLarge, maxSize)
+	}
+	if len(data) == 0 {
+		return schema.Attachment{}, ErrEmptyAttachment
+	}
+
+	detected := mimetype.Detect(data)
+	if !r.allowedAttachmentType(detected) {
+		return schema.Attachment{}, fmt.Errorf("%w: %s", ErrAttachmentType, detected.String())
+	}
+
+	sum := sha256.Sum256(data)
+
+	var attachment schema.Attachment
+	stored := false
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := editableApplication(tx, user, applicationId)
+		if err != nil {
+			return err
+		}
+
+		attachment = schema.Attachment{
+			ApplicationRefer: int(app.Id),
+			UploaderRefer:    int(user.Id),
+			Name:             attachmentName(upload.Name, detected),
+			MimeType:         detected.String(),
+			Size:             int64(len(data)),
+			Checksum:         hex.EncodeToString(sum[:]),
+			StorageKey:       fmt.Sprintf("applications/%d/%s", app.Id, uuid.NewString()),
+		}
+
+		if upload.LineId != 0 {
+			var line schema.Kingdom2Application
+			err = forUpdate(tx).
+				Where("id = ? AND application_refer = ?", upload.LineId, app.Id).
+				First(&line).Error
+			if err != nil {
+				return err
+			}
+
+			lineId := int(line.Id)
+			attachment.LineRefer = &lineId
+		}
+
+		err = r.files.Put(attachment.StorageKey, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		stored = true
+
+		err = tx.Create(&attachment).Error
+		if err != nil {
+			return err
+		}
+
+		err = touchApplication(tx, app.Id)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: int(app.Id),
+			ActorRefer:       int(user.Id),
+			Type:             EventAttachmentAdd,
+		}, nil, attachment.Name)
+	})
+	if err != nil {
+		if stored {
+			_ = r.files.Delete(attachment.StorageKey)
+		}
+
+		return schema.Attachment{}, err
+	}
+
+	return attachment, nil
+}
+
+func (r *Repository) GetAttachments(user schema.User, applicationId string) ([]schema.Attachment, error) {
+	var tx *gorm.DB = r.db
+
+	app, err := readableApplication(tx, user, applicationId)
+	if err != nil {
+		return []schema.Attachment{}, err
+	}
+
+	var attachmentsToReturn []schema.Attachment
+	err = tx.Where("application_refer = ?", app.Id).Order("id").Find(&attachmentsToReturn).Error
+	if err != nil {
+		return []schema.Attachment{}, err
+	}
+
+	return attachmentsToReturn, nil
+}
+
+// OpenAttachment returns the attachment with its content; the caller closes it.
+func (r *Repository) OpenAttachment(user schema.User, applicationId string,
+	attachmentId string) (schema.Attachment, io.ReadCloser, error) {
+
+	var tx *gorm.DB = r.db
+
+	app, err := readableApplication(tx, user, applicationId)
+	if err != nil {
+		return schema.Attachment{}, nil, err
+	}
+
+	var attachment schema.Attachment
+	err = tx.Where("id = ? AND application_refer = ?", attachmentId, app.Id).First(&attachment).Error
+	if err != nil {
+		return schema.Attachment{}, nil, err
+	}
+
+	content, err := r.files.Open(attachment.StorageKey)
+	if err != nil {
+		return schema.Attachment{}, nil, err
+	}
+
+	return attachment, content, nil
+}
+
+func (r *Repository) DeleteAttachment(user schema.User, applicationId string, attachmentId string) error {
+	var attachment schema.Attachment
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := editableApplication(tx, user, applicationId)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("id = ? AND application_refer = ?", attachmentId, app.Id).First(&attachment).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete(&attachment).Error
+		if err != nil {
+			return err
+		}
+
+		err = touchApplication(tx, app.Id)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: int(app.Id),
+			ActorRefer:       int(user.Id),
+			Type:             EventAttachmentRemove,
+		}, attachment.Name, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	// the row is gone, a file left behind is only wasted space
+	_ = r.files.Delete(attachment.StorageKey)
+
+	return nil
+}
+
+// lineAttachments groups the attachments of an application by the line they
+// support.
+func lineAttachments(tx *gorm.DB, applicationId interface{}) (map[int][]schema.Attachment, error) {
+	var attachments []schema.Attachment
+	err := tx.Where("application_refer = ? AND line_refer IS NOT NULL", applicationId).
+		Order("id").
+		Find(&attachments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byLine := make(map[int][]schema.Attachment)
+	for _, attachment := range attachments {
+		byLine[*attachment.LineRefer] = append(byLine[*attachment.LineRefer], attachment)
+	}
+
+	return byLine, nil
+}
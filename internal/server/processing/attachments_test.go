@@ -0,0 +1,59 @@
+package processing
+
+import (
+	"strings"
+	"testing"
+
+	"kingdoms/internal/config"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+func TestAttachmentName(t *testing.T) {
+	pdf := mimetype.Lookup("application/pdf")
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"летопись.pdf", "летопись.pdf"},
+		{"  scan.pdf  ", "scan.pdf"},
+		{"docs/scan.pdf", "scan.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\boyar\scan.pdf`, "scan.pdf"},
+		{"/", "attachment.pdf"},
+		{".", "attachment.pdf"},
+		{"", "attachment.pdf"},
+		{"   ", "attachment.pdf"},
+		{strings.Repeat("я", 300), strings.Repeat("я", maxAttachmentNameLength)},
+	}
+
+	for _, tt := range tests {
+		if got := attachmentName(tt.name, pdf); got != tt.want {
+			t.Errorf("attachmentName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedAttachmentType(t *testing.T) {
+	r := &Repository{cfg: &config.Config{Attachments: config.AttachmentsConfig{
+		AllowedTypes: []string{"application/pdf", "image/png"},
+	}}}
+
+	tests := []struct {
+		content string
+		allowed bool
+	}{
+		{"%PDF-1.7\n", true},
+		{"\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", true},
+		{"plain text", false},
+		{"MZ\x90\x00\x03\x00\x00\x00", false},
+	}
+
+	for _, tt := range tests {
+		detected := mimetype.Detect([]byte(tt.content))
+		if got := r.allowedAttachmentType(detected); got != tt.allowed {
+			t.Errorf("allowedAttachmentType(%s) = %v, want %v", detected, got, tt.allowed)
+		}
+	}
+}
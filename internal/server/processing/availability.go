@@ -0,0 +1,175 @@
+package processing
+
+import (
+	"sort"
+	"time"
+
+	"kingdoms/internal/server/app/appState"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// claimingStates are the states whose kingdom periods count as taken.
+var claimingStates = []appState.State{appState.Submitted, appState.Approved}
+
+// earliestDate and latestDate bound the whole calendar when looking for claims.
+var (
+	earliestDate = datatypes.Date(time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC))
+	latestDate   = datatypes.Date(time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC))
+)
+
+func dayOf(date datatypes.Date) time.Time {
+	t := time.Time(date)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween counts the days from one midnight to another. It goes through
+// Unix seconds because time.Duration only spans about 292 years.
+func daysBetween(from time.Time, to time.Time) int {
+	return int((to.Unix() - from.Unix()) / (24 * 60 * 60))
+}
+
+// claimedPeriods returns the periods of kingdomId claimed by submitted or
+// approved applications that overlap [from, to], ordered by their start.
+func claimedPeriods(tx *gorm.DB, kingdomId int, from datatypes.Date, to datatypes.Date) ([]ClaimedPeriod, error) {
+	periods := []ClaimedPeriod{}
+
+	err := overlappingClaims(tx, kingdomId, 0, from, to, claimingStates).
+		Select(`kingdom2_applications.application_refer AS application_id, ruler_applications.state AS state,
+			kingdom2_applications."from" AS "from", kingdom2_applications."to" AS "to"`).
+		Order(`kingdom2_applications."from", kingdom2_applications.application_refer`).
+		Scan(&periods).Error
+	if err != nil {
+		return []ClaimedPeriod{}, err
+	}
+
+	return periods, nil
+}
+
+// freePeriods returns the gaps between claimed periods inside [from, to].
+// Periods include both of their ends, so gaps start the day after a claim ends.
+func freePeriods(claimed []ClaimedPeriod, from time.Time, to time.Time) []FreePeriod {
+	free := []FreePeriod{}
+
+	cursor := from
+	for _, claim := range claimed {
+		claimFrom, claimTo := dayOf(claim.From), dayOf(claim.To)
+
+		if claimFrom.After(cursor) {
+			free = append(free, FreePeriod{
+				From: datatypes.Date(cursor),
+				To:   datatypes.Date(claimFrom.AddDate(0, 0, -1)),
+			})
+		}
+
+		if next := claimTo.AddDate(0, 0, 1); next.After(cursor) {
+			cursor = next
+		}
+	}
+
+	if !cursor.After(to) {
+		free = append(free, FreePeriod{From: datatypes.Date(cursor), To: datatypes.Date(to)})
+	}
+
+	return free
+}
+
+// nearestFreePeriod finds the free period lasting as long as [from, to] whose
+// start is closest to from. A free period always exists: at the latest it
+// starts right after the last claim.
+func nearestFreePeriod(claimed []ClaimedPeriod, from time.Time, to time.Time) FreePeriod {
+	length := daysBetween(from, to)
+
+	isFree := func(start time.Time) bool {
+		end := start.AddDate(0, 0, length)
+		for _, claim := range claimed {
+			if !dayOf(claim.From).After(end) && !dayOf(claim.To).Before(start) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	candidates := []time.Time{from}
+	for _, claim := range claimed {
+		candidates = append(candidates,
+			dayOf(claim.To).AddDate(0, 0, 1),
+			dayOf(claim.From).AddDate(0, 0, -length-1))
+	}
+
+	distance := func(start time.Time) int {
+		if start.Before(from) {
+			return daysBetween(start, from)
+		}
+
+		return daysBetween(from, start)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return distance(candidates[i]) < distance(candidates[j])
+	})
+
+	for _, start := range candidates {
+		if isFree(start) {
+			return FreePeriod{From: datatypes.Date(start), To: datatypes.Date(start.AddDate(0, 0, length))}
+		}
+	}
+
+	// unreachable: the candidate after the latest claim is always free
+	return FreePeriod{From: datatypes.Date(from), To: datatypes.Date(to)}
+}
+
+// GetKingdomAvailability lists the claimed periods of the kingdom and the free
+// gaps between them inside [from, to]. With days set it also suggests the
+// free period of that many days nearest to from.
+func (r *Repository) GetKingdomAvailability(kingdomId uint, from datatypes.Date, to datatypes.Date,
+	days int) (KingdomAvailability, error) {
+
+	err := validatePeriod(from, to)
+	if err != nil {
+		return KingdomAvailability{}, err
+	}
+
+	claimed, err := claimedPeriods(r.db, int(kingdomId), from, to)
+	if err != nil {
+		return KingdomAvailability{}, err
+	}
+
+	availability := KingdomAvailability{
+		KingdomId: kingdomId,
+		From:      from,
+		To:        to,
+		Claimed:   claimed,
+		Free:      freePeriods(claimed, dayOf(from), dayOf(to)),
+	}
+
+	if days > 0 {
+		suggestion, err := r.SuggestFreePeriod(kingdomId, from,
+			datatypes.Date(dayOf(from).AddDate(0, 0, days-1)))
+		if err != nil {
+			return KingdomAvailability{}, err
+		}
+
+		availability.Suggestion = &suggestion
+	}
+
+	return availability, nil
+}
+
+// SuggestFreePeriod finds the free period as long as [from, to] that starts
+// nearest to from.
+func (r *Repository) SuggestFreePeriod(kingdomId uint, from datatypes.Date, to datatypes.Date) (FreePeriod, error) {
+	err := validatePeriod(from, to)
+	if err != nil {
+		return FreePeriod{}, err
+	}
+
+	claimed, err := claimedPeriods(r.db, int(kingdomId), earliestDate, latestDate)
+	if err != nil {
+		return FreePeriod{}, err
+	}
+
+	return nearestFreePeriod(claimed, dayOf(from), dayOf(to)), nil
+}
@@ -0,0 +1,106 @@
+package processing
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+func date(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func claim(from time.Time, to time.Time) ClaimedPeriod {
+	return ClaimedPeriod{From: datatypes.Date(from), To: datatypes.Date(to)}
+}
+
+func TestNearestFreePeriod(t *testing.T) {
+	tests := []struct {
+		name     string
+		claimed  []ClaimedPeriod
+		from     time.Time
+		to       time.Time
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{
+			name:     "nothing claimed",
+			from:     date(2000, 1, 10),
+			to:       date(2000, 1, 12),
+			wantFrom: date(2000, 1, 10),
+			wantTo:   date(2000, 1, 12),
+		},
+		{
+			name:     "after the claim is nearer",
+			claimed:  []ClaimedPeriod{claim(date(2000, 1, 1), date(2000, 1, 15))},
+			from:     date(2000, 1, 10),
+			to:       date(2000, 1, 12),
+			wantFrom: date(2000, 1, 16),
+			wantTo:   date(2000, 1, 18),
+		},
+		{
+			name:     "before the claim is nearer",
+			claimed:  []ClaimedPeriod{claim(date(2000, 1, 5), date(2000, 1, 30))},
+			from:     date(2000, 1, 7),
+			to:       date(2000, 1, 8),
+			wantFrom: date(2000, 1, 3),
+			wantTo:   date(2000, 1, 4),
+		},
+		{
+			name:     "gap too short between claims",
+			claimed:  []ClaimedPeriod{claim(date(2000, 1, 1), date(2000, 1, 10)), claim(date(2000, 1, 13), date(2000, 2, 1))},
+			from:     date(2000, 1, 5),
+			to:       date(2000, 1, 9),
+			wantFrom: date(1999, 12, 27),
+			wantTo:   date(1999, 12, 31),
+		},
+		{
+			name:     "period longer than 300 years",
+			claimed:  []ClaimedPeriod{claim(date(1000, 6, 1), date(1000, 6, 30))},
+			from:     date(1000, 1, 1),
+			to:       date(1400, 12, 31),
+			wantFrom: date(1000, 7, 1),
+			wantTo:   date(1401, 6, 30),
+		},
+		{
+			name:     "candidates more than 300 years away",
+			claimed:  []ClaimedPeriod{claim(date(1200, 1, 1), date(1900, 1, 1))},
+			from:     date(1500, 1, 1),
+			to:       date(1500, 1, 10),
+			wantFrom: date(1199, 12, 22),
+			wantTo:   date(1199, 12, 31),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nearestFreePeriod(tt.claimed, tt.from, tt.to)
+			if !time.Time(got.From).Equal(tt.wantFrom) || !time.Time(got.To).Equal(tt.wantTo) {
+				t.Errorf("nearestFreePeriod() = %s..%s, want %s..%s",
+					time.Time(got.From).Format("2006-01-02"), time.Time(got.To).Format("2006-01-02"),
+					tt.wantFrom.Format("2006-01-02"), tt.wantTo.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	tests := []struct {
+		from time.Time
+		to   time.Time
+		want int
+	}{
+		{date(2000, 1, 1), date(2000, 1, 1), 0},
+		{date(2000, 2, 28), date(2000, 3, 1), 2},
+		{date(1, 1, 1), date(1990, 1, 1), 726467},
+		{date(1000, 1, 1), date(1400, 12, 31), 146461},
+	}
+
+	for _, tt := range tests {
+		if got := daysBetween(tt.from, tt.to); got != tt.want {
+			t.Errorf("daysBetween(%s, %s) = %d, want %d",
+				tt.from.Format("2006-01-02"), tt.to.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
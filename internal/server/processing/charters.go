@@ -0,0 +1,279 @@
+package processing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"kingdoms/internal/charter"
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/app/policy"
+	"kingdoms/internal/storage"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var ErrNoCharter = errors.New("only approved applications have a charter")
+
+// issueCharter gives the application a new charter code as it is approved
+// and freezes what the charter says: renaming a kingdom or editing the ruler
+// later does not change a granted charter. The PDF itself is rendered on first
+// download.
+func issueCharter(tx *gorm.DB, applicationId uint) (schema.Charter, error) {
+	var app schema.RulerApplication
+	err := tx.Where("id = ?", applicationId).First(&app).Error
+	if err != nil {
+		return schema.Charter{}, err
+	}
+
+	content, err := charterContent(tx, app)
+	if err != nil {
+		return schema.Charter{}, err
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return schema.Charter{}, err
+	}
+
+	code := make([]byte, 16)
+	_, err = rand.Read(code)
+	if err != nil {
+		return schema.Charter{}, err
+	}
+
+	issued := schema.Charter{
+		ApplicationRefer: int(applicationId),
+		Code:             hex.EncodeToString(code),
+		Content:          data,
+	}
+
+	err = tx.Create(&issued).Error
+	if err != nil {
+		return schema.Charter{}, err
+	}
+
+	return issued, nil
+}
+
+// revokeCharters invalidates the charters of an application leaving approval,
+// so a charter printed before cannot be passed off as still valid.
+func revokeCharters(tx *gorm.DB, applicationId uint) error {
+	return tx.Model(&schema.Charter{}).
+		Where("application_refer = ? AND date_revoked IS NULL", applicationId).
+		Update("date_revoked", time.Now().UTC()).Error
+}
+
+func (r *Repository) charterVerifyURL(code string) string {
+	return strings.TrimRight(r.cfg.Charter.PublicURL, "/") + "/charter/" + code + "/verify"
+}
+
+// charterContent collects what the charter of an approved application says.
+func charterContent(tx *gorm.DB, app schema.RulerApplication) (charter.Charter, error) {
+	var lines []schema.Kingdom2Application
+	err := tx.Where("application_refer = ?", app.Id).
+		Order(`"from", id`).
+		Preload("Kingdom").
+		Find(&lines).Error
+	if err != nil {
+		return charter.Charter{}, err
+	}
+
+	content := charter.Charter{
+		Ruler:        app.Ruler,
+		Kingdoms:     []charter.Kingdom{},
+		DecisionDate: app.DateComplete,
+	}
+	for _, line := range lines {
+		content.Kingdoms = append(content.Kingdoms, charter.Kingdom{
+			Name: line.Kingdom.Name,
+			From: time.Time(line.From),
+			To:   time.Time(line.To),
+		})
+	}
+
+	if app.ModeratorRefer != nil {
+		var moderator schema.User
+		err = tx.Select("id, name").Where("id = ?", *app.ModeratorRefer).First(&moderator).Error
+		if err != nil {
+			return charter.Charter{}, err
+		}
+
+		content.Moderator = moderator.Name
+	}
+
+	return content, nil
+}
+
+// issuedContent returns what the charter says as it was issued. Charters
+// issued before their content was frozen say what the application says now.
+func issuedContent(tx *gorm.DB, issued schema.Charter, app schema.RulerApplication) (charter.Charter, error) {
+	if len(issued.Content) == 0 {
+		return charterContent(tx, app)
+	}
+
+	var content charter.Charter
+	err := json.Unmarshal(issued.Content, &content)
+	if err != nil {
+		return charter.Charter{}, err
+	}
+
+	return content, nil
+}
+
+// GetCharterPDF returns the charter of an approved application, rendering it
+// the first time and serving the stored file afterwards. The application row
+// is locked throughout, so concurrent first downloads neither issue a second
+// charter nor render it twice.
+func (r *Repository) GetCharterPDF(user schema.User, applicationId string) ([]byte, error) {
+	var pdf []byte
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := lockedApplication(tx, applicationId)
+		if err != nil {
+			return err
+		}
+
+		err = policy.Authorize(user, policy.ReadApplication, &app)
+		if err != nil {
+			return err
+		}
+
+		if app.State != appState.Approved {
+			return ErrNoCharter
+		}
+
+		var current schema.Charter
+		err = tx.Where("application_refer = ? AND date_revoked IS NULL", app.Id).
+			Order("id DESC").
+			First(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// approved before charters were issued
+			current, err = issueCharter(tx, app.Id)
+		}
+		if err != nil {
+			return err
+		}
+
+		if current.StorageKey != "" {
+			content, err := r.files.Open(current.StorageKey)
+			if err == nil {
+				defer content.Close()
+				pdf, err = io.ReadAll(content)
+				return err
+			}
+			if !errors.Is(err, storage.ErrNotFound) {
+				return err
+			}
+		}
+
+		err = r.dropRevokedCharterFiles(tx, app.Id)
+		if err != nil {
+			return err
+		}
+
+		content, err := issuedContent(tx, current, app)
+		if err != nil {
+			return err
+		}
+		content.Code = current.Code
+		content.VerifyURL = r.charterVerifyURL(current.Code)
+
+		pdf, err = charter.Render(content, r.cfg.Charter.FontPath)
+		if err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("charters/%d/%s.pdf", app.Id, current.Code)
+		err = r.files.Put(key, bytes.NewReader(pdf))
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&schema.Charter{}).Where("id = ?", current.Id).Update("storage_key", key).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pdf, nil
+}
+
+// dropRevokedCharterFiles removes the stored PDFs of charters revoked when the
+// application was reopened; the charter rows stay for verification.
+func (r *Repository) dropRevokedCharterFiles(tx *gorm.DB, applicationId uint) error {
+	var revoked []schema.Charter
+	err := tx.Where("application_refer = ? AND date_revoked IS NOT NULL AND storage_key != ''", applicationId).
+		Find(&revoked).Error
+	if err != nil {
+		return err
+	}
+
+	for _, old := range revoked {
+		err = r.files.Delete(old.StorageKey)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&schema.Charter{}).Where("id = ?", old.Id).Update("storage_key", "").Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// VerifyCharter tells anyone holding a charter code whether the charter is
+// valid. Details are given only for valid charters.
+func (r *Repository) VerifyCharter(code string) (CharterVerification, error) {
+	var tx *gorm.DB = r.db
+
+	var issued schema.Charter
+	err := tx.Where("code = ?", code).First(&issued).Error
+	if err != nil {
+		return CharterVerification{}, err
+	}
+
+	var app schema.RulerApplication
+	err = tx.Where("id = ?", issued.ApplicationRefer).First(&app).Error
+	if err != nil {
+		return CharterVerification{}, err
+	}
+
+	verification := CharterVerification{
+		Code:        issued.Code,
+		Valid:       issued.DateRevoked == nil && app.State == appState.Approved,
+		DateRevoked: issued.DateRevoked,
+	}
+	if !verification.Valid {
+		return verification, nil
+	}
+
+	content, err := issuedContent(tx, issued, app)
+	if err != nil {
+		return CharterVerification{}, err
+	}
+
+	verification.Ruler = content.Ruler
+	verification.Kingdoms = []CharterKingdom{}
+	for _, kingdom := range content.Kingdoms {
+		verification.Kingdoms = append(verification.Kingdoms, CharterKingdom{
+			Name: kingdom.Name,
+			From: datatypes.Date(kingdom.From),
+			To:   datatypes.Date(kingdom.To),
+		})
+	}
+	verification.Moderator = content.Moderator
+	verification.DecisionDate = &content.DecisionDate
+
+	return verification, nil
+}
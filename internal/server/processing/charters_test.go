@@ -0,0 +1,58 @@
+package processing
+
+import (
+	"testing"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	role "kingdoms/internal/server/app/userRole"
+)
+
+func TestCharterKeepsIssuedContent(t *testing.T) {
+	r := testRepository(t)
+	owner := testUser(t, r, "owner", role.Buyer)
+	manager := testUser(t, r, "manager", role.Manager)
+	kiev := testKingdom(t, r, "Киевское", 100)
+	app := testApplication(t, r, owner, appState.Submitted, kiev)
+
+	err := testDecision(r, manager, app.Id, appState.Approved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var issued schema.Charter
+	err = r.db.Where("application_refer = ?", app.Id).First(&issued).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.db.Model(&schema.Kingdom{}).Where("id = ?", kiev.Id).Update("name", "Великое Киевское").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.db.Model(&schema.RulerApplication{}).Where("id = ?", app.Id).Update("ruler", "Ярополк").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verification, err := r.VerifyCharter(issued.Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !verification.Valid {
+		t.Fatal("the charter of an approved application is not valid")
+	}
+	if verification.Ruler != app.Ruler {
+		t.Errorf("Ruler = %q, want %q as approved", verification.Ruler, app.Ruler)
+	}
+	if len(verification.Kingdoms) != 1 || verification.Kingdoms[0].Name != "Киевское" {
+		t.Errorf("Kingdoms = %+v, want Киевское as approved", verification.Kingdoms)
+	}
+	if verification.Moderator != manager.Name {
+		t.Errorf("Moderator = %q, want %q", verification.Moderator, manager.Name)
+	}
+	if verification.DecisionDate == nil || verification.DecisionDate.IsZero() {
+		t.Errorf("DecisionDate = %v, want the approval date", verification.DecisionDate)
+	}
+}
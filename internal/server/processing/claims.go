@@ -0,0 +1,149 @@
+package processing
+
+import (
+	"errors"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/app/policy"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPeriod  = errors.New("kingdom period must have a start not later than its end")
+	ErrPeriodConflict = errors.New("kingdom period overlaps a period granted by an approved application")
+)
+
+func validatePeriod(from datatypes.Date, to datatypes.Date) error {
+	if time.Time(from).IsZero() || time.Time(to).IsZero() || time.Time(from).After(time.Time(to)) {
+		return ErrInvalidPeriod
+	}
+
+	return nil
+}
+
+// overlappingClaims selects the kingdom lines of applications in states that
+// claim the kingdom for a period overlapping [from, to]. Both ends are inclusive.
+func overlappingClaims(tx *gorm.DB, kingdomId int, applicationId int, from datatypes.Date, to datatypes.Date,
+	states []appState.State) *gorm.DB {
+
+	return tx.Model(&schema.Kingdom2Application{}).
+		Joins("JOIN ruler_applications ON ruler_applications.id = kingdom2_applications.application_refer").
+		Where("kingdom2_applications.kingdom_refer = ?", kingdomId).
+		Where("kingdom2_applications.application_refer != ?", applicationId).
+		Where(`kingdom2_applications."from" <= ? AND kingdom2_applications."to" >= ?`, to, from).
+		Where("ruler_applications.state IN ?", states)
+}
+
+// checkKingdomPeriod validates a kingdom line before it is written: the period
+// must be well ordered and, when configured, free of approved claims.
+func (r *Repository) checkKingdomPeriod(tx *gorm.DB, line schema.Kingdom2Application) error {
+	err := validatePeriod(line.From, line.To)
+	if err != nil {
+		return err
+	}
+
+	if !r.cfg.Claims.BlockApprovedOverlaps {
+		return nil
+	}
+
+	var count int64
+	err = overlappingClaims(tx, line.KingdomRefer, line.ApplicationRefer, line.From, line.To,
+		[]appState.State{appState.Approved}).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+
+	if count != 0 {
+		return ErrPeriodConflict
+	}
+
+	return nil
+}
+
+// checkApprovedOverlaps re-checks the lines of an application about to be
+// approved: another application may have been granted one of its kingdoms for
+// an overlapping period since the lines were written. The kingdoms are locked
+// first, so two such approvals run one after another and the later one sees
+// the earlier.
+func (r *Repository) checkApprovedOverlaps(tx *gorm.DB, app schema.RulerApplication) error {
+	if !r.cfg.Claims.BlockApprovedOverlaps {
+		return nil
+	}
+
+	var lines []schema.Kingdom2Application
+	err := tx.Where("application_refer = ?", app.Id).Order("kingdom_refer, id").Find(&lines).Error
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	kingdomIds := make([]int, 0, len(lines))
+	for _, line := range lines {
+		kingdomIds = append(kingdomIds, line.KingdomRefer)
+	}
+
+	var kingdoms []schema.Kingdom
+	err = forUpdate(tx).Select("id").Where("id IN ?", kingdomIds).Order("id").Find(&kingdoms).Error
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		var count int64
+		err = overlappingClaims(tx, line.KingdomRefer, int(app.Id), line.From, line.To,
+			[]appState.State{appState.Approved}).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+
+		if count != 0 {
+			return ErrPeriodConflict
+		}
+	}
+
+	return nil
+}
+
+// GetClaimConflicts lists every pair of submitted or approved applications
+// that claim the same kingdom for overlapping periods.
+func (r *Repository) GetClaimConflicts(user schema.User) ([]ClaimConflict, error) {
+	conflictsToReturn := []ClaimConflict{}
+
+	err := policy.Authorize(user, policy.ListApplications, nil)
+	if err != nil {
+		return []ClaimConflict{}, err
+	}
+
+	var tx *gorm.DB = r.db
+
+	err = tx.Table("kingdom2_applications AS first").
+		Select(`kingdoms.id AS kingdom_id, kingdoms.name AS kingdom_name,
+			first.application_refer AS first_application_id, first_application.state AS first_state,
+			first."from" AS first_from, first."to" AS first_to,
+			second.application_refer AS second_application_id, second_application.state AS second_state,
+			second."from" AS second_from, second."to" AS second_to`).
+		Joins(`JOIN kingdom2_applications AS second ON second.kingdom_refer = first.kingdom_refer
+			AND second.application_refer > first.application_refer
+			AND first."from" <= second."to" AND second."from" <= first."to"`).
+		Joins("JOIN ruler_applications AS first_application ON first_application.id = first.application_refer").
+		Joins("JOIN ruler_applications AS second_application ON second_application.id = second.application_refer").
+		Joins("JOIN kingdoms ON kingdoms.id = first.kingdom_refer").
+		Where("first_application.state IN ? AND second_application.state IN ?",
+			[]appState.State{appState.Submitted, appState.Approved},
+			[]appState.State{appState.Submitted, appState.Approved}).
+		Order("kingdoms.id, first.application_refer, second.application_refer").
+		Scan(&conflictsToReturn).Error
+	if err != nil {
+		return []ClaimConflict{}, err
+	}
+
+	return conflictsToReturn, nil
+}
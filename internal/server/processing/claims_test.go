@@ -0,0 +1,48 @@
+package processing
+
+import (
+	"errors"
+	"testing"
+
+	"kingdoms/internal/server/app/appState"
+	role "kingdoms/internal/server/app/userRole"
+)
+
+func TestApprovalRechecksApprovedOverlaps(t *testing.T) {
+	r := testRepository(t)
+	r.cfg.Claims.BlockApprovedOverlaps = true
+
+	owner := testUser(t, r, "owner", role.Buyer)
+	rival := testUser(t, r, "rival", role.Buyer)
+	manager := testUser(t, r, "manager", role.Manager)
+	kiev := testKingdom(t, r, "Киевское", 100)
+	chernigov := testKingdom(t, r, "Черниговское", 100)
+
+	// both claims were written while neither was approved
+	granted := testApplication(t, r, rival, appState.Submitted, kiev)
+	app := testApplication(t, r, owner, appState.Submitted, chernigov)
+	testLine(t, r, app, kiev, date(1132, 6, 1), date(1133, 6, 1))
+	later := testApplication(t, r, owner, appState.Submitted)
+	testLine(t, r, later, kiev, date(1133, 1, 1), date(1134, 1, 1))
+
+	err := testDecision(r, manager, granted.Id, appState.Approved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = testDecision(r, manager, app.Id, appState.Approved)
+	if !errors.Is(err, ErrPeriodConflict) {
+		t.Errorf("approving an overlapping claim: error = %v, want ErrPeriodConflict", err)
+	}
+
+	err = testDecision(r, manager, later.Id, appState.Approved)
+	if err != nil {
+		t.Errorf("approving a claim after the granted period: error = %v", err)
+	}
+
+	r.cfg.Claims.BlockApprovedOverlaps = false
+	err = testDecision(r, manager, app.Id, appState.Approved)
+	if err != nil {
+		t.Errorf("approving an overlapping claim with overlaps allowed: error = %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package processing
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/policy"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	CloneFail  = "fail"
+	CloneMerge = "merge"
+)
+
+var ErrDraftExists = errors.New("user already has a draft application")
+
+func shiftDate(date datatypes.Date, days int) datatypes.Date {
+	return datatypes.Date(time.Time(date).AddDate(0, 0, days))
+}
+
+// CloneApplication copies the ruler and kingdom lines of an application into
+// the caller's draft. A new draft is created unless the caller already has
+// one: then the clone fails or, in merge mode, fills the existing draft.
+// Lines the draft cannot take are skipped and reported.
+func (r *Repository) CloneApplication(user schema.User, applicationId string,
+	cloneApplication CloneApplication) (ClonedApplication, error) {
+
+	if cloneApplication.Mode == "" {
+		cloneApplication.Mode = CloneFail
+	}
+	if cloneApplication.Mode != CloneFail && cloneApplication.Mode != CloneMerge {
+		return ClonedApplication{}, fmt.Errorf("%w: mode must be %s or %s", ErrInvalidQuery, CloneFail, CloneMerge)
+	}
+
+	cloned := ClonedApplication{Skipped: []SkippedLine{}}
+	var draftId uint
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var source schema.RulerApplication
+		err := tx.Where("id = ?", applicationId).First(&source).Error
+		if err != nil {
+			return err
+		}
+
+		err = policy.Authorize(user, policy.CloneApplication, &source)
+		if err != nil {
+			return err
+		}
+
+		draft, err := userDraft(tx, user, false)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			draft, err = createApplication(tx, user)
+			if err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case cloneApplication.Mode == CloneFail || draft.Id == source.Id:
+			return ErrDraftExists
+		}
+		draftId = draft.Id
+
+		if draft.Ruler == "" && source.Ruler != "" {
+			err = tx.Model(&schema.RulerApplication{}).
+				Where("id = ?", draft.Id).
+				Update("ruler", source.Ruler).Error
+			if err != nil {
+				return err
+			}
+
+			err = recordEvent(tx, &schema.ApplicationEvent{
+				ApplicationRefer: int(draft.Id),
+				ActorRefer:       int(user.Id),
+				Type:             EventRulerUpdate,
+			}, draft.Ruler, source.Ruler)
+			if err != nil {
+				return err
+			}
+		}
+
+		var lines []schema.Kingdom2Application
+		err = tx.Where("application_refer = ?", source.Id).Order("id").Find(&lines).Error
+		if err != nil {
+			return err
+		}
+
+		var present []int
+		err = tx.Model(&schema.Kingdom2Application{}).
+			Where("application_refer = ?", draft.Id).
+			Pluck("kingdom_refer", &present).Error
+		if err != nil {
+			return err
+		}
+
+		inDraft := make(map[int]bool, len(present))
+		for _, kingdomId := range present {
+			inDraft[kingdomId] = true
+		}
+
+		for _, line := range lines {
+			if inDraft[line.KingdomRefer] {
+				cloned.Skipped = append(cloned.Skipped, SkippedLine{
+					KingdomId: uint(line.KingdomRefer),
+					Reason:    "kingdom is already in the draft",
+				})
+				continue
+			}
+
+			copied := schema.Kingdom2Application{
+				ApplicationRefer: int(draft.Id),
+				KingdomRefer:     line.KingdomRefer,
+				From:             shiftDate(line.From, cloneApplication.ShiftDays),
+				To:               shiftDate(line.To, cloneApplication.ShiftDays),
+				Justification:    line.Justification,
+				Sources:          line.Sources,
+				Decision:         LinePending,
+			}
+
+			err = r.checkKingdomPeriod(tx, copied)
+			if errors.Is(err, ErrPeriodConflict) || errors.Is(err, ErrInvalidPeriod) {
+				cloned.Skipped = append(cloned.Skipped, SkippedLine{
+					KingdomId: uint(line.KingdomRefer),
+					Reason:    err.Error(),
+				})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			err = tx.Create(&copied).Error
+			if err != nil {
+				return err
+			}
+			inDraft[copied.KingdomRefer] = true
+
+			err = recordEvent(tx, &schema.ApplicationEvent{
+				ApplicationRefer: copied.ApplicationRefer,
+				ActorRefer:       int(user.Id),
+				Type:             EventKingdomAdd,
+				KingdomRefer:     &copied.KingdomRefer,
+			}, nil, kingdomPeriod{From: copied.From, To: copied.To})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = touchApplication(tx, draft.Id)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: int(draft.Id),
+			ActorRefer:       int(user.Id),
+			Type:             EventCloned,
+		}, nil, source.Id)
+	})
+	if err != nil {
+		return ClonedApplication{}, err
+	}
+
+	cloned.Application, err = r.GetApplicationWithKingdoms(user, strconv.Itoa(int(draftId)))
+	if err != nil {
+		return ClonedApplication{}, err
+	}
+
+	return cloned, nil
+}
@@ -0,0 +1,299 @@
+package processing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/app/policy"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotDraft         = errors.New("only drafts can be edited")
+	ErrAlreadyInvited   = errors.New("user is already invited")
+	ErrInviteSelf       = errors.New("the owner cannot be a co-author")
+	ErrInvitationClosed = errors.New("invitation was already answered")
+	ErrNotCoAuthor      = errors.New("user is not a co-author of the application")
+)
+
+func publicUser(db *gorm.DB) *gorm.DB {
+	return db.Select("id, uuid, name, role")
+}
+
+// loadCoAuthors fills app.CoAuthors with the accepted co-authors, which the
+// policy looks at to tell co-authors apart.
+func loadCoAuthors(tx *gorm.DB, app *schema.RulerApplication) error {
+	return tx.Where("application_refer = ? AND state = ?", app.Id, schema.CoAuthorAccepted).
+		Order("id").
+		Preload("User", publicUser).
+		Find(&app.CoAuthors).Error
+}
+
+// editableApplication returns the application with its row locked if user may
+// edit it. Only drafts are edited, by the owner as by co-authors: anything
+// submitted goes through moderation unchanged.
+func editableApplication(tx *gorm.DB, user schema.User, applicationId interface{}) (schema.RulerApplication, error) {
+	app, err := lockedApplication(tx, applicationId)
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	err = policy.Authorize(user, policy.EditApplication, &app)
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	if app.State != appState.Draft {
+		return schema.RulerApplication{}, ErrNotDraft
+	}
+
+	return app, nil
+}
+
+// draftToEdit returns the draft a kingdom line is edited in with its row
+// locked: the one given by id, which co-authors name, or else the user's own.
+func draftToEdit(tx *gorm.DB, user schema.User, applicationId uint, create bool) (schema.RulerApplication, error) {
+	if applicationId == 0 {
+		app, err := userDraft(tx, user, create)
+		if err != nil {
+			return schema.RulerApplication{}, err
+		}
+
+		err = policy.Authorize(user, policy.EditApplication, &app)
+		if err != nil {
+			return schema.RulerApplication{}, err
+		}
+
+		return app, nil
+	}
+
+	return editableApplication(tx, user, applicationId)
+}
+
+// InviteCoAuthor invites a user, by name, to edit the owner's draft.
+// Someone who declined or was removed may be invited again.
+func (r *Repository) InviteCoAuthor(user schema.User, applicationId string,
+	invitation CoAuthorInvitation) (schema.ApplicationCoAuthor, error) {
+
+	var invited schema.ApplicationCoAuthor
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := lockedApplication(tx, applicationId)
+		if err != nil {
+			return err
+		}
+
+		err = policy.Authorize(user, policy.ManageCoAuthors, &app)
+		if err != nil {
+			return err
+		}
+
+		if app.State != appState.Draft {
+			return ErrNotDraft
+		}
+
+		var invitee schema.User
+		err = publicUser(tx).Where("name = ?", invitation.UserName).First(&invitee).Error
+		if err != nil {
+			return err
+		}
+
+		if int(invitee.Id) == app.CreatorRefer {
+			return ErrInviteSelf
+		}
+
+		err = forUpdate(tx).
+			Where("application_refer = ? AND user_refer = ?", app.Id, invitee.Id).
+			First(&invited).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			invited = schema.ApplicationCoAuthor{
+				ApplicationRefer: int(app.Id),
+				UserRefer:        int(invitee.Id),
+				InviterRefer:     int(user.Id),
+				State:            schema.CoAuthorPending,
+			}
+			err = tx.Create(&invited).Error
+		case err != nil:
+		case invited.State != schema.CoAuthorDeclined:
+			err = ErrAlreadyInvited
+		default:
+			err = tx.Model(&invited).Updates(map[string]interface{}{
+				"state":         schema.CoAuthorPending,
+				"inviter_refer": user.Id,
+				"date_create":   time.Now().UTC(),
+				"date_answer":   nil,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+		invited.User = invitee
+
+		err = notify(tx, int(invitee.Id), NotificationCoAuthorInvite, app.Id,
+			fmt.Sprintf("%s invites you to co-author the application of %s", user.Name, app.Ruler))
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: int(app.Id),
+			ActorRefer:       int(user.Id),
+			Type:             EventCoAuthorInvite,
+		}, nil, invitee.Name)
+	})
+	if err != nil {
+		return schema.ApplicationCoAuthor{}, err
+	}
+
+	return invited, nil
+}
+
+// GetCoAuthors lists the co-authors of an application together with the
+// invitations still open.
+func (r *Repository) GetCoAuthors(user schema.User, applicationId string) ([]schema.ApplicationCoAuthor, error) {
+	var tx *gorm.DB = r.db
+
+	app, err := readableApplication(tx, user, applicationId)
+	if err != nil {
+		return []schema.ApplicationCoAuthor{}, err
+	}
+
+	var coAuthorsToReturn []schema.ApplicationCoAuthor
+	err = tx.Where("application_refer = ? AND state != ?", app.Id, schema.CoAuthorDeclined).
+		Order("id").
+		Preload("User", publicUser).
+		Preload("Inviter", publicUser).
+		Find(&coAuthorsToReturn).Error
+	if err != nil {
+		return []schema.ApplicationCoAuthor{}, err
+	}
+
+	return coAuthorsToReturn, nil
+}
+
+// RemoveCoAuthor takes the edit rights away from a co-author or withdraws an
+// invitation. The owner removes anyone; a co-author may only leave.
+func (r *Repository) RemoveCoAuthor(user schema.User, applicationId string, userId string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := lockedApplication(tx, applicationId)
+		if err != nil {
+			return err
+		}
+
+		var coAuthor schema.ApplicationCoAuthor
+		err = forUpdate(tx).
+			Where("application_refer = ? AND user_refer = ?", app.Id, userId).
+			Preload("User", publicUser).
+			First(&coAuthor).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotCoAuthor
+		}
+		if err != nil {
+			return err
+		}
+
+		if coAuthor.UserRefer != int(user.Id) {
+			err = policy.Authorize(user, policy.ManageCoAuthors, &app)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Delete(&coAuthor).Error
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: int(app.Id),
+			ActorRefer:       int(user.Id),
+			Type:             EventCoAuthorRemove,
+		}, coAuthor.User.Name, nil)
+	})
+}
+
+// GetInvitations returns the co-author invitations waiting for user's answer.
+func (r *Repository) GetInvitations(user schema.User) ([]schema.ApplicationCoAuthor, error) {
+	var tx *gorm.DB = r.db
+
+	var invitationsToReturn []schema.ApplicationCoAuthor
+	err := tx.Where("user_refer = ? AND state = ?", user.Id, schema.CoAuthorPending).
+		Order("date_create, id").
+		Preload("Inviter", publicUser).
+		Find(&invitationsToReturn).Error
+	if err != nil {
+		return []schema.ApplicationCoAuthor{}, err
+	}
+
+	return invitationsToReturn, nil
+}
+
+// AnswerInvitation accepts or declines an invitation addressed to user. An
+// invitation to an application that has left draft can no longer be accepted:
+// accepting closes it as declined and returns ErrNotDraft.
+func (r *Repository) AnswerInvitation(user schema.User, invitationId string, answer InvitationAnswer) error {
+	closed := false
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var found schema.ApplicationCoAuthor
+		err := tx.Select("application_refer").
+			Where("id = ? AND user_refer = ?", invitationId, user.Id).
+			First(&found).Error
+		if err != nil {
+			return err
+		}
+
+		// the application is locked before its co-authors, as when inviting
+		// and removing them
+		app, err := lockedApplication(tx, found.ApplicationRefer)
+		if err != nil {
+			return err
+		}
+
+		var invitation schema.ApplicationCoAuthor
+		err = forUpdate(tx).
+			Where("id = ? AND user_refer = ?", invitationId, user.Id).
+			First(&invitation).Error
+		if err != nil {
+			return err
+		}
+
+		if invitation.State != schema.CoAuthorPending {
+			return ErrInvitationClosed
+		}
+
+		state := schema.CoAuthorDeclined
+		if answer.Accept && app.State == appState.Draft {
+			state = schema.CoAuthorAccepted
+		}
+		closed = answer.Accept && state == schema.CoAuthorDeclined
+
+		err = tx.Model(&invitation).Updates(map[string]interface{}{
+			"state":       state,
+			"date_answer": time.Now().UTC(),
+		}).Error
+		if err != nil || state != schema.CoAuthorAccepted {
+			return err
+		}
+
+		return recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: invitation.ApplicationRefer,
+			ActorRefer:       int(user.Id),
+			Type:             EventCoAuthorJoin,
+		}, nil, user.Name)
+	})
+	if err != nil {
+		return err
+	}
+
+	if closed {
+		return ErrNotDraft
+	}
+
+	return nil
+}
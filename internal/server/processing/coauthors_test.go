@@ -0,0 +1,70 @@
+package processing
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	role "kingdoms/internal/server/app/userRole"
+)
+
+func TestAnswerInvitation(t *testing.T) {
+	r := testRepository(t)
+	owner := testUser(t, r, "owner", role.Buyer)
+	invitee := testUser(t, r, "invitee", role.Buyer)
+
+	invite := func(app schema.RulerApplication) string {
+		invitation := schema.ApplicationCoAuthor{
+			ApplicationRefer: int(app.Id),
+			UserRefer:        int(invitee.Id),
+			InviterRefer:     int(owner.Id),
+			State:            schema.CoAuthorPending,
+		}
+		err := r.db.Create(&invitation).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return strconv.FormatUint(uint64(invitation.Id), 10)
+	}
+	invitationState := func(id string) string {
+		var invitation schema.ApplicationCoAuthor
+		err := r.db.Where("id = ?", id).First(&invitation).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return invitation.State
+	}
+
+	draft := invite(testApplication(t, r, owner, appState.Draft))
+	err := r.AnswerInvitation(invitee, draft, InvitationAnswer{Accept: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := invitationState(draft); state != schema.CoAuthorAccepted {
+		t.Errorf("invitation to a draft is %s, want accepted", state)
+	}
+
+	err = r.AnswerInvitation(invitee, draft, InvitationAnswer{Accept: false})
+	if !errors.Is(err, ErrInvitationClosed) {
+		t.Errorf("answering twice: error = %v, want ErrInvitationClosed", err)
+	}
+
+	submitted := invite(testApplication(t, r, owner, appState.Submitted))
+	err = r.AnswerInvitation(invitee, submitted, InvitationAnswer{Accept: true})
+	if !errors.Is(err, ErrNotDraft) {
+		t.Errorf("accepting for a submitted application: error = %v, want ErrNotDraft", err)
+	}
+	if state := invitationState(submitted); state != schema.CoAuthorDeclined {
+		t.Errorf("invitation to a submitted application is %s, want it closed as declined", state)
+	}
+
+	declined := invite(testApplication(t, r, owner, appState.Approved))
+	err = r.AnswerInvitation(invitee, declined, InvitationAnswer{Accept: false})
+	if err != nil {
+		t.Errorf("declining for an approved application: error = %v", err)
+	}
+}
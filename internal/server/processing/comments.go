@@ -0,0 +1,195 @@
+package processing
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/policy"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEditWindowClosed = errors.New("comment can no longer be changed")
+	ErrEmptyComment     = errors.New("comment is empty")
+)
+
+// addDecisionComment puts the comment given with a decision into the
+// discussion thread, linked to that decision and, if given, to the line decided.
+func addDecisionComment(tx *gorm.DB, event schema.ApplicationEvent, lineRefer *int) error {
+	text := strings.TrimSpace(event.Comment)
+	if text == "" {
+		return nil
+	}
+
+	eventId := int(event.Id)
+	comment := schema.ApplicationComment{
+		ApplicationRefer: event.ApplicationRefer,
+		AuthorRefer:      event.ActorRefer,
+		EventRefer:       &eventId,
+		LineRefer:        lineRefer,
+		Text:             text,
+	}
+
+	return tx.Create(&comment).Error
+}
+
+// commentableApplication returns the application if user may take part in its
+// discussion: the owner and moderators may.
+func commentableApplication(tx *gorm.DB, user schema.User, applicationId string) (schema.RulerApplication, error) {
+	var app schema.RulerApplication
+	err := tx.Where("id = ?", applicationId).First(&app).Error
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	err = loadCoAuthors(tx, &app)
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	err = policy.Authorize(user, policy.Discuss, &app)
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	return app, nil
+}
+
+func (r *Repository) GetApplicationComments(user schema.User, applicationId string) ([]schema.ApplicationComment, error) {
+	var tx *gorm.DB = r.db
+
+	app, err := commentableApplication(tx, user, applicationId)
+	if err != nil {
+		return []schema.ApplicationComment{}, err
+	}
+
+	var commentsToReturn []schema.ApplicationComment
+	err = tx.Where("application_refer = ?", app.Id).
+		Order("date_create, id").
+		Preload("Author", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, uuid, name, role")
+		}).
+		Find(&commentsToReturn).Error
+	if err != nil {
+		return []schema.ApplicationComment{}, err
+	}
+
+	return commentsToReturn, nil
+}
+
+func (r *Repository) CreateApplicationComment(user schema.User, applicationId string,
+	commentToCreate CommentToUpdate) (schema.ApplicationComment, error) {
+
+	text := strings.TrimSpace(commentToCreate.Text)
+	if text == "" {
+		return schema.ApplicationComment{}, ErrEmptyComment
+	}
+
+	var comment schema.ApplicationComment
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := commentableApplication(tx, user, applicationId)
+		if err != nil {
+			return err
+		}
+
+		comment = schema.ApplicationComment{
+			ApplicationRefer: int(app.Id),
+			AuthorRefer:      int(user.Id),
+			Text:             text,
+		}
+
+		if commentToCreate.LineId != 0 {
+			// the line must stay until the comment referring to it is written
+			var line schema.Kingdom2Application
+			err = forUpdate(tx).
+				Where("id = ? AND application_refer = ?", commentToCreate.LineId, app.Id).
+				First(&line).Error
+			if err != nil {
+				return err
+			}
+
+			lineId := int(line.Id)
+			comment.LineRefer = &lineId
+		}
+
+		return tx.Create(&comment).Error
+	})
+	if err != nil {
+		return schema.ApplicationComment{}, err
+	}
+
+	return comment, nil
+}
+
+// editableComment returns the comment if user may still change it: authors
+// within the edit window, and moderators removing someone else's comment.
+// Either has to be still taking part in the discussion: a co-author who was
+// removed no longer changes what they wrote.
+func editableComment(tx *gorm.DB, user schema.User, applicationId string, commentId string,
+	editWindow time.Duration, deleting bool) (schema.ApplicationComment, error) {
+
+	app, err := commentableApplication(tx, user, applicationId)
+	if err != nil {
+		return schema.ApplicationComment{}, err
+	}
+
+	var comment schema.ApplicationComment
+	err = forUpdate(tx).
+		Where("id = ? AND application_refer = ?", commentId, app.Id).
+		First(&comment).Error
+	if err != nil {
+		return schema.ApplicationComment{}, err
+	}
+
+	if comment.AuthorRefer != int(user.Id) {
+		if deleting && policy.Can(user, policy.ModerateComments, &app) {
+			return comment, nil
+		}
+
+		return schema.ApplicationComment{}, ErrForbidden
+	}
+
+	if time.Since(comment.DateCreate) > editWindow {
+		return schema.ApplicationComment{}, ErrEditWindowClosed
+	}
+
+	return comment, nil
+}
+
+func (r *Repository) UpdateApplicationComment(user schema.User, applicationId string, commentId string,
+	commentToUpdate CommentToUpdate) error {
+
+	text := strings.TrimSpace(commentToUpdate.Text)
+	if text == "" {
+		return ErrEmptyComment
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		comment, err := editableComment(tx, user, applicationId, commentId, r.cfg.Comments.EditWindow, false)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&schema.ApplicationComment{}).
+			Where("id = ?", comment.Id).
+			Updates(map[string]interface{}{
+				"text":        text,
+				"date_update": time.Now().UTC(),
+			}).Error
+	})
+}
+
+func (r *Repository) DeleteApplicationComment(user schema.User, applicationId string, commentId string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		comment, err := editableComment(tx, user, applicationId, commentId, r.cfg.Comments.EditWindow, true)
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&comment).Error
+	})
+}
@@ -0,0 +1,59 @@
+package processing
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/app/policy"
+	role "kingdoms/internal/server/app/userRole"
+)
+
+func TestRemovedCoAuthorCannotChangeComments(t *testing.T) {
+	r := testRepository(t)
+	r.cfg.Comments.EditWindow = time.Hour
+
+	owner := testUser(t, r, "owner", role.Buyer)
+	coAuthor := testUser(t, r, "co-author", role.Buyer)
+	app := testApplication(t, r, owner, appState.Draft)
+	id := strconv.FormatUint(uint64(app.Id), 10)
+
+	invitation := schema.ApplicationCoAuthor{
+		ApplicationRefer: int(app.Id),
+		UserRefer:        int(coAuthor.Id),
+		InviterRefer:     int(owner.Id),
+		State:            schema.CoAuthorAccepted,
+	}
+	err := r.db.Create(&invitation).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comment, err := r.CreateApplicationComment(coAuthor, id, CommentToUpdate{Text: "Летопись приложена"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commentId := strconv.FormatUint(uint64(comment.Id), 10)
+
+	err = r.UpdateApplicationComment(coAuthor, id, commentId, CommentToUpdate{Text: "Летописи приложены"})
+	if err != nil {
+		t.Fatalf("co-author edits own comment: %v", err)
+	}
+
+	err = r.db.Delete(&invitation).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.UpdateApplicationComment(coAuthor, id, commentId, CommentToUpdate{Text: "Летописи нет"})
+	if !errors.Is(err, policy.ErrForbidden) {
+		t.Errorf("removed co-author edits: error = %v, want ErrForbidden", err)
+	}
+	err = r.DeleteApplicationComment(coAuthor, id, commentId)
+	if !errors.Is(err, policy.ErrForbidden) {
+		t.Errorf("removed co-author deletes: error = %v, want ErrForbidden", err)
+	}
+}
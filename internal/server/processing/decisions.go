@@ -0,0 +1,49 @@
+package processing
+
+import (
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+
+	"gorm.io/gorm"
+)
+
+// DecideApplications applies one moderator decision to many applications in a
+// single transaction. Each application gets its own savepoint, so one refused
+// decision does not undo the others; the returned results follow the order of
+// ids.
+func (r *Repository) DecideApplications(user schema.User, ids []uint, state string,
+	comment string) ([]DecisionResult, error) {
+
+	target, err := appState.Parse(state)
+	if err != nil {
+		return []DecisionResult{}, err
+	}
+
+	results := make([]DecisionResult, 0, len(ids))
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			err := tx.SavePoint("decision").Error
+			if err != nil {
+				return err
+			}
+
+			decisionErr := r.applyModeratorDecision(tx, user, id, target, comment)
+			if decisionErr != nil {
+				err = tx.RollbackTo("decision").Error
+				if err != nil {
+					return err
+				}
+			}
+
+			results = append(results, DecisionResult{Id: id, Err: decisionErr})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return []DecisionResult{}, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,242 @@
+package processing
+
+import (
+	"fmt"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/app/policy"
+
+	"gorm.io/gorm"
+)
+
+const (
+	NotificationDraftExpiring  = "draft_expiring"
+	NotificationDraftExpired   = "draft_expired"
+	NotificationCoAuthorInvite = "co_author_invite"
+)
+
+const day = 24 * time.Hour
+
+// touchApplication marks the application as just changed, which restarts the
+// expiry period of a draft.
+func touchApplication(tx *gorm.DB, applicationId uint) error {
+	return tx.Model(&schema.RulerApplication{}).
+		Where("id = ?", applicationId).
+		Updates(map[string]interface{}{
+			"last_modified": time.Now().UTC(),
+			"date_warned":   nil,
+		}).Error
+}
+
+// draftExpiresAt is when the draft expires unless it is changed: the idle
+// period after its last change, but never sooner than the warning period
+// after the owner was warned or would be warned now.
+func (r *Repository) draftExpiresAt(lastModified time.Time, dateWarned *time.Time, now time.Time) time.Time {
+	expiresAt := lastModified.Add(time.Duration(r.cfg.Drafts.ExpireAfterDays) * day)
+
+	warned := now
+	if dateWarned != nil {
+		warned = *dateWarned
+	}
+
+	if notice := warned.Add(time.Duration(r.cfg.Drafts.WarnBeforeDays) * day); notice.After(expiresAt) {
+		return notice
+	}
+
+	return expiresAt
+}
+
+func notify(tx *gorm.DB, userId int, kind string, applicationId uint, text string) error {
+	appId := int(applicationId)
+
+	return tx.Create(&schema.Notification{
+		UserRefer:        userId,
+		Type:             kind,
+		ApplicationRefer: &appId,
+		Text:             text,
+	}).Error
+}
+
+// ExpireDrafts warns the owners of drafts that are about to expire and moves
+// drafts whose owners were warned long enough ago to the trash.
+func (r *Repository) ExpireDrafts() (DraftExpiryRun, error) {
+	run := DraftExpiryRun{Warned: []uint{}, Expired: []uint{}}
+	if r.cfg.Drafts.ExpireAfterDays <= 0 {
+		return run, nil
+	}
+
+	now := time.Now().UTC()
+	expireBefore := now.Add(-time.Duration(r.cfg.Drafts.ExpireAfterDays) * day)
+	warnBefore := expireBefore.Add(time.Duration(r.cfg.Drafts.WarnBeforeDays) * day)
+	noticeGivenBefore := now.Add(-time.Duration(r.cfg.Drafts.WarnBeforeDays) * day)
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var expiring []schema.RulerApplication
+		err := forUpdate(tx).
+			Where("state = ? AND last_modified < ? AND date_warned IS NULL", appState.Draft, warnBefore).
+			Order("id").
+			Find(&expiring).Error
+		if err != nil {
+			return err
+		}
+
+		for _, app := range expiring {
+			expiresAt := r.draftExpiresAt(app.LastModified, nil, now)
+
+			err = notify(tx, app.CreatorRefer, NotificationDraftExpiring, app.Id,
+				fmt.Sprintf("Draft application %d has not changed since %s and will be moved to the trash on %s unless it is edited.",
+					app.Id, app.LastModified.Format("2006-01-02"), expiresAt.Format("2006-01-02")))
+			if err != nil {
+				return err
+			}
+
+			err = tx.Model(&schema.RulerApplication{}).
+				Where("id = ?", app.Id).
+				Update("date_warned", now).Error
+			if err != nil {
+				return err
+			}
+
+			run.Warned = append(run.Warned, app.Id)
+		}
+
+		var expired []schema.RulerApplication
+		err = forUpdate(tx).
+			Where("state = ? AND last_modified < ? AND date_warned < ?", appState.Draft, expireBefore, noticeGivenBefore).
+			Order("id").
+			Find(&expired).Error
+		if err != nil {
+			return err
+		}
+
+		for _, app := range expired {
+			updates := map[string]interface{}{
+				"state": appState.Deleted,
+			}
+			trashUpdates(app, updates)
+
+			err = applyTransition(tx, app, updates)
+			if err != nil {
+				return err
+			}
+
+			// nobody acted: the expiry is recorded on behalf of the owner
+			err = recordEvent(tx, &schema.ApplicationEvent{
+				ApplicationRefer: int(app.Id),
+				ActorRefer:       app.CreatorRefer,
+				Type:             EventExpired,
+			}, app.State, appState.Deleted)
+			if err != nil {
+				return err
+			}
+
+			err = notify(tx, app.CreatorRefer, NotificationDraftExpired, app.Id,
+				fmt.Sprintf("Draft application %d has not changed since %s and was moved to the trash.",
+					app.Id, app.LastModified.Format("2006-01-02")))
+			if err != nil {
+				return err
+			}
+
+			run.Expired = append(run.Expired, app.Id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return DraftExpiryRun{}, err
+	}
+
+	return run, nil
+}
+
+// GetDraftsOverview describes the draft population: how many drafts there
+// are, how long they have been idle and which expire within days.
+func (r *Repository) GetDraftsOverview(user schema.User, days int) (DraftsOverview, error) {
+	err := policy.Authorize(user, policy.ManageDrafts, nil)
+	if err != nil {
+		return DraftsOverview{}, err
+	}
+
+	var tx *gorm.DB = r.db
+	now := time.Now().UTC()
+	drafts := func() *gorm.DB {
+		return tx.Model(&schema.RulerApplication{}).Where("ruler_applications.state = ?", appState.Draft)
+	}
+
+	overview := DraftsOverview{Idle: []IdleDrafts{}, Upcoming: []DraftExpiry{}}
+
+	err = drafts().Count(&overview.Total).Error
+	if err != nil {
+		return DraftsOverview{}, err
+	}
+
+	err = drafts().Where("date_warned IS NOT NULL").Count(&overview.Warned).Error
+	if err != nil {
+		return DraftsOverview{}, err
+	}
+
+	for _, idleDays := range []int{7, 30, r.cfg.Drafts.ExpireAfterDays} {
+		idle := IdleDrafts{Days: idleDays}
+
+		err = drafts().Where("last_modified < ?", now.Add(-time.Duration(idleDays)*day)).Count(&idle.Count).Error
+		if err != nil {
+			return DraftsOverview{}, err
+		}
+
+		overview.Idle = append(overview.Idle, idle)
+	}
+
+	if r.cfg.Drafts.ExpireAfterDays <= 0 {
+		return overview, nil
+	}
+
+	// the earliest a draft changed after this can expire is beyond the horizon
+	horizon := now.Add(time.Duration(days) * day)
+	lastModifiedBefore := horizon.Add(-time.Duration(r.cfg.Drafts.ExpireAfterDays) * day)
+
+	var stale []schema.RulerApplication
+	err = drafts().
+		Where("last_modified < ?", lastModifiedBefore).
+		Order("last_modified, id").
+		Preload("Creator").
+		Find(&stale).Error
+	if err != nil {
+		return DraftsOverview{}, err
+	}
+
+	for _, app := range stale {
+		expiresAt := r.draftExpiresAt(app.LastModified, app.DateWarned, now)
+		if expiresAt.After(horizon) {
+			continue
+		}
+
+		overview.Upcoming = append(overview.Upcoming, DraftExpiry{
+			ApplicationId: app.Id,
+			CreatorId:     app.Creator.Id,
+			CreatorName:   app.Creator.Name,
+			Ruler:         app.Ruler,
+			LastModified:  app.LastModified,
+			DateWarned:    app.DateWarned,
+			ExpiresAt:     expiresAt,
+		})
+	}
+
+	return overview, nil
+}
+
+func (r *Repository) GetNotifications(user schema.User) ([]schema.Notification, error) {
+	var tx *gorm.DB = r.db
+
+	notificationsToReturn := []schema.Notification{}
+	err := tx.Where("user_refer = ?", user.Id).
+		Order("date_create DESC, id DESC").
+		Limit(100).
+		Find(&notificationsToReturn).Error
+	if err != nil {
+		return []schema.Notification{}, err
+	}
+
+	return notificationsToReturn, nil
+}
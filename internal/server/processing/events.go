@@ -0,0 +1,92 @@
+package processing
+
+import (
+	"encoding/json"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/policy"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	EventCreated          = "created"
+	EventStateChange      = "state_change"
+	EventRulerUpdate      = "ruler_update"
+	EventKingdomAdd       = "kingdom_add"
+	EventKingdomUpdate    = "kingdom_update"
+	EventKingdomRemove    = "kingdom_remove"
+	EventCloned           = "cloned"
+	EventExpired          = "expired"
+	EventLineDecision     = "line_decision"
+	EventAttachmentAdd    = "attachment_add"
+	EventAttachmentRemove = "attachment_remove"
+	EventApprovalStage    = "approval_stage"
+	EventCoAuthorInvite   = "co_author_invite"
+	EventCoAuthorJoin     = "co_author_join"
+	EventCoAuthorRemove   = "co_author_remove"
+)
+
+// ErrForbidden is returned when the policy refuses an action.
+var ErrForbidden = policy.ErrForbidden
+
+type kingdomPeriod struct {
+	From datatypes.Date
+	To   datatypes.Date
+}
+
+// recordEvent appends an entry to the application history. Values are stored
+// as JSON, nil meaning there was no value before or after the change.
+func recordEvent(tx *gorm.DB, event *schema.ApplicationEvent, oldValue interface{}, newValue interface{}) error {
+	var err error
+
+	if oldValue != nil {
+		event.OldValue, err = json.Marshal(oldValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	if newValue != nil {
+		event.NewValue, err = json.Marshal(newValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Create(event).Error
+}
+
+func (r *Repository) GetApplicationHistory(user schema.User, applicationId string) ([]schema.ApplicationEvent, error) {
+	var tx *gorm.DB = r.db
+
+	var app schema.RulerApplication
+	err := tx.Where("id = ?", applicationId).First(&app).Error
+	if err != nil {
+		return []schema.ApplicationEvent{}, err
+	}
+
+	err = loadCoAuthors(tx, &app)
+	if err != nil {
+		return []schema.ApplicationEvent{}, err
+	}
+
+	err = policy.Authorize(user, policy.ReadHistory, &app)
+	if err != nil {
+		return []schema.ApplicationEvent{}, err
+	}
+
+	var eventsToReturn []schema.ApplicationEvent
+	err = tx.Where("application_refer = ?", app.Id).
+		Order("date_create, id").
+		Preload("Actor", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, uuid, name, role")
+		}).
+		Find(&eventsToReturn).Error
+	if err != nil {
+		return []schema.ApplicationEvent{}, err
+	}
+
+	return eventsToReturn, nil
+}
@@ -0,0 +1,70 @@
+package processing
+
+import (
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/locale"
+)
+
+// exportBatchSize is how many kingdoms the export translates with one query.
+const exportBatchSize = 500
+
+// ExportKingdoms walks the feed straight from a database cursor and hands the
+// kingdoms to each one at a time, so the catalog is never held in memory.
+// Translations are looked up a batch of kingdoms at a time for the same reason.
+func (r *Repository) ExportKingdoms(kingdomName string, withImages bool, loc locale.Locale,
+	each func(kingdom schema.Kingdom) error) error {
+
+	query := kingdomsFeedQuery(r.db, kingdomName)
+	if !withImages {
+		query = query.Omit("image")
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batch := make([]schema.Kingdom, 0, exportBatchSize)
+	flush := func() error {
+		err := r.TranslateKingdoms(batch, loc)
+		if err != nil {
+			return err
+		}
+
+		for _, kingdom := range batch {
+			err = each(kingdom)
+			if err != nil {
+				return err
+			}
+		}
+
+		batch = batch[:0]
+
+		return nil
+	}
+
+	for rows.Next() {
+		var kingdom schema.Kingdom
+
+		err = r.db.ScanRows(rows, &kingdom)
+		if err != nil {
+			return err
+		}
+
+		batch = append(batch, kingdom)
+		if len(batch) == exportBatchSize {
+			err = flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	return flush()
+}
@@ -0,0 +1,52 @@
+package processing
+
+import (
+	"fmt"
+	"testing"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/locale"
+)
+
+func TestExportKingdomsTranslates(t *testing.T) {
+	r := testRepository(t)
+
+	// enough kingdoms for two batches
+	for i := 0; i < exportBatchSize+2; i++ {
+		kingdom := testKingdom(t, r, fmt.Sprintf("Княжество %d", i), 100)
+		if i%2 == 1 {
+			continue
+		}
+
+		err := r.db.Create(&schema.KingdomTranslation{
+			KingdomRefer: int(kingdom.Id),
+			Locale:       string(locale.En),
+			Name:         fmt.Sprintf("Principality %d", i),
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var exported []schema.Kingdom
+	err := r.ExportKingdoms("", false, locale.En, func(kingdom schema.Kingdom) error {
+		exported = append(exported, kingdom)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exported) != exportBatchSize+2 {
+		t.Fatalf("exported %d kingdoms, want %d", len(exported), exportBatchSize+2)
+	}
+	for i, kingdom := range exported {
+		want := fmt.Sprintf("Княжество %d", i)
+		if i%2 == 0 {
+			want = fmt.Sprintf("Principality %d", i)
+		}
+		if kingdom.Name != want || kingdom.Capital != fmt.Sprintf("Княжество %d", i) {
+			t.Fatalf("kingdom %d = %q, capital %q, want %q with the capital untranslated", i, kingdom.Name, kingdom.Capital, want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package processing
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"kingdoms/internal/database/schema"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+	ImportError   = "error"
+)
+
+var errImportRolledBack = errors.New("import rolled back")
+
+// ImportKingdoms upserts kingdoms by name in a single transaction. Nothing is
+// committed when the run is a dry run or when any row fails.
+func (r *Repository) ImportKingdoms(rows []KingdomImportRow, dryRun bool) (KingdomImportReport, error) {
+	report := KingdomImportReport{
+		DryRun: dryRun,
+		Rows:   make([]KingdomImportResult, 0, len(rows)),
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			result := KingdomImportResult{
+				Row:  row.Row,
+				Name: row.Kingdom.Name,
+			}
+
+			if row.Error != "" {
+				result.Result, result.Reason = ImportError, row.Error
+			} else {
+				err := tx.SavePoint("import_row").Error
+				if err != nil {
+					return err
+				}
+
+				result.Result, result.Reason = importKingdomRow(tx, row.Kingdom)
+				if result.Result == ImportError {
+					err = tx.RollbackTo("import_row").Error
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			switch result.Result {
+			case ImportCreated:
+				report.Created++
+			case ImportUpdated:
+				report.Updated++
+			case ImportSkipped:
+				report.Skipped++
+			default:
+				report.Errors++
+			}
+
+			report.Rows = append(report.Rows, result)
+		}
+
+		if dryRun || report.Errors != 0 {
+			return errImportRolledBack
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRolledBack) {
+		return KingdomImportReport{}, err
+	}
+
+	report.Committed = err == nil
+
+	return report, nil
+}
+
+func importKingdomRow(tx *gorm.DB, kingdom schema.Kingdom) (string, string) {
+	kingdom.Name = strings.TrimSpace(kingdom.Name)
+	if kingdom.Name == "" {
+		return ImportError, "name is empty"
+	}
+	if reason := validateKingdomLengths(kingdom); reason != "" {
+		return ImportError, reason
+	}
+	if kingdom.Area < 0 {
+		return ImportError, "area is negative"
+	}
+	if kingdom.Latitude != nil && (*kingdom.Latitude < -90 || *kingdom.Latitude > 90) {
+		return ImportError, "latitude is out of range"
+	}
+	if kingdom.Longitude != nil && (*kingdom.Longitude < -180 || *kingdom.Longitude > 180) {
+		return ImportError, "longitude is out of range"
+	}
+
+	var existing schema.Kingdom
+	err := tx.Where("name = ?", kingdom.Name).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if kingdom.Capital == "" {
+			return ImportError, "capital is empty"
+		}
+		if kingdom.State == "" {
+			return ImportError, "state is empty"
+		}
+
+		kingdom.Id = 0
+		kingdom.Slug, err = uniqueKingdomSlug(tx, kingdom.Name, 0)
+		if err != nil {
+			return ImportError, err.Error()
+		}
+
+		err = tx.Create(&kingdom).Error
+		if err != nil {
+			return ImportError, err.Error()
+		}
+
+		return ImportCreated, ""
+	}
+	if err != nil {
+		return ImportError, err.Error()
+	}
+
+	if (kingdom.Area == 0 || kingdom.Area == existing.Area) &&
+		(kingdom.Capital == "" || kingdom.Capital == existing.Capital) &&
+		(kingdom.Image == "" || kingdom.Image == existing.Image) &&
+		(kingdom.Description == "" || kingdom.Description == existing.Description) &&
+		(kingdom.State == "" || kingdom.State == existing.State) &&
+		sameCoordinate(kingdom.Latitude, existing.Latitude) &&
+		sameCoordinate(kingdom.Longitude, existing.Longitude) {
+		return ImportSkipped, "no changes"
+	}
+
+	kingdom.Id = existing.Id
+	kingdom.Slug = ""
+
+	err = tx.Model(&schema.Kingdom{}).
+		Where("id = ?", existing.Id).
+		Updates(kingdom).Error
+	if err != nil {
+		return ImportError, err.Error()
+	}
+
+	return ImportUpdated, ""
+}
+
+// sameCoordinate reports whether importing coordinate changes nothing: an
+// absent value keeps whatever is stored.
+func sameCoordinate(coordinate *float64, existing *float64) bool {
+	return coordinate == nil || existing != nil && *coordinate == *existing
+}
+
+func validateKingdomLengths(kingdom schema.Kingdom) string {
+	switch {
+	case utf8.RuneCountInString(kingdom.Name) > 100:
+		return "name is longer than 100 characters"
+	case utf8.RuneCountInString(kingdom.Capital) > 50:
+		return "capital is longer than 50 characters"
+	case utf8.RuneCountInString(kingdom.Description) > 255:
+		return "description is longer than 255 characters"
+	case utf8.RuneCountInString(kingdom.State) > 50:
+		return "state is longer than 50 characters"
+	}
+
+	return ""
+}
@@ -0,0 +1,204 @@
+package processing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/app/policy"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	LinePending  = "pending"
+	LineApproved = "approved"
+	LineRejected = "rejected"
+
+	maxJustificationLength = 5000
+	maxSources             = 20
+	maxSourceLength        = 500
+)
+
+var (
+	ErrInvalidEvidence     = errors.New("invalid justification or sources")
+	ErrUnknownLineDecision = errors.New("unknown line decision")
+	ErrLineNotUnderReview  = errors.New("lines are decided only while the application is submitted")
+	ErrNothingApproved     = errors.New("every kingdom line is rejected")
+)
+
+// lineEvidence checks the justification and sources the owner gave for a line
+// and returns them in the shape they are stored in.
+func lineEvidence(kingdomAddToApplication KingdomAddToApplication) (string, datatypes.JSON, error) {
+	justification := strings.TrimSpace(kingdomAddToApplication.Justification)
+	if len([]rune(justification)) > maxJustificationLength {
+		return "", nil, fmt.Errorf("%w: justification is longer than %d characters",
+			ErrInvalidEvidence, maxJustificationLength)
+	}
+
+	if len(kingdomAddToApplication.Sources) == 0 {
+		return justification, nil, nil
+	}
+
+	if len(kingdomAddToApplication.Sources) > maxSources {
+		return "", nil, fmt.Errorf("%w: more than %d sources", ErrInvalidEvidence, maxSources)
+	}
+
+	sources := make([]string, 0, len(kingdomAddToApplication.Sources))
+	for _, source := range kingdomAddToApplication.Sources {
+		source = strings.TrimSpace(source)
+		if source == "" {
+			return "", nil, fmt.Errorf("%w: empty source", ErrInvalidEvidence)
+		}
+		if len([]rune(source)) > maxSourceLength {
+			return "", nil, fmt.Errorf("%w: source is longer than %d characters",
+				ErrInvalidEvidence, maxSourceLength)
+		}
+
+		sources = append(sources, source)
+	}
+
+	data, err := json.Marshal(sources)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return justification, data, nil
+}
+
+// lineSources decodes the stored sources of a line, which are empty for lines
+// added before sources existed.
+func lineSources(data datatypes.JSON) []string {
+	sources := []string{}
+	if len(data) == 0 {
+		return sources
+	}
+
+	_ = json.Unmarshal(data, &sources)
+	return sources
+}
+
+// pruneRejectedLines removes the lines moderators rejected from an application
+// about to be approved, so only the approved part of it is granted.
+func pruneRejectedLines(tx *gorm.DB, user schema.User, app schema.RulerApplication) error {
+	var lines []schema.Kingdom2Application
+	err := forUpdate(tx).
+		Where("application_refer = ?", app.Id).
+		Order("id").
+		Find(&lines).Error
+	if err != nil {
+		return err
+	}
+
+	var rejected []schema.Kingdom2Application
+	for _, line := range lines {
+		if line.Decision == LineRejected {
+			rejected = append(rejected, line)
+		}
+	}
+
+	if len(rejected) == 0 {
+		return nil
+	}
+	if len(rejected) == len(lines) {
+		return ErrNothingApproved
+	}
+
+	for _, line := range rejected {
+		err = tx.Delete(&line).Error
+		if err != nil {
+			return err
+		}
+
+		kingdomId := line.KingdomRefer
+		err = recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: int(app.Id),
+			ActorRefer:       int(user.Id),
+			Type:             EventKingdomRemove,
+			KingdomRefer:     &kingdomId,
+			Comment:          line.DecisionComment,
+		}, kingdomPeriod{From: line.From, To: line.To}, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DecideApplicationLine records a moderator's verdict on one kingdom line of a
+// submitted application. Rejecting a line needs a comment explaining why.
+func (r *Repository) DecideApplicationLine(user schema.User, applicationId uint, lineId uint,
+	lineDecision LineDecision) error {
+
+	switch lineDecision.Decision {
+	case LinePending, LineApproved, LineRejected:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownLineDecision, lineDecision.Decision)
+	}
+
+	comment := strings.TrimSpace(lineDecision.Comment)
+	if lineDecision.Decision == LineRejected && comment == "" {
+		return ErrEmptyComment
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := lockedApplication(tx, applicationId)
+		if err != nil {
+			return err
+		}
+
+		err = policy.Authorize(user, policy.DecideApplication, &app)
+		if err != nil {
+			return err
+		}
+
+		if app.State != appState.Submitted {
+			return ErrLineNotUnderReview
+		}
+
+		var line schema.Kingdom2Application
+		err = forUpdate(tx).
+			Where("id = ? AND application_refer = ?", lineId, app.Id).
+			First(&line).Error
+		if err != nil {
+			return err
+		}
+
+		var decider *int
+		if lineDecision.Decision != LinePending {
+			userId := int(user.Id)
+			decider = &userId
+		}
+
+		err = tx.Model(&schema.Kingdom2Application{}).
+			Where("id = ?", line.Id).
+			Updates(map[string]interface{}{
+				"decision":         lineDecision.Decision,
+				"decision_comment": comment,
+				"decider_refer":    decider,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		event := schema.ApplicationEvent{
+			ApplicationRefer: int(app.Id),
+			ActorRefer:       int(user.Id),
+			Type:             EventLineDecision,
+			KingdomRefer:     &line.KingdomRefer,
+			Comment:          comment,
+		}
+		err = recordEvent(tx, &event, line.Decision, lineDecision.Decision)
+		if err != nil {
+			return err
+		}
+
+		lineRefer := int(line.Id)
+		return addDecisionComment(tx, event, &lineRefer)
+	})
+}
@@ -0,0 +1,58 @@
+package processing
+
+import (
+	"errors"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// forUpdate locks the rows a query reads until the transaction ends.
+func forUpdate(tx *gorm.DB) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// lockedApplication reads an application and locks its row, so concurrent
+// mutations of the same application run one after another.
+func lockedApplication(tx *gorm.DB, applicationId interface{}) (schema.RulerApplication, error) {
+	var app schema.RulerApplication
+	err := forUpdate(tx).Where("id = ?", applicationId).First(&app).Error
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	err = loadCoAuthors(tx, &app)
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	return app, nil
+}
+
+// userDraft returns the user's draft with its row locked, creating it when
+// create is set. The user's row is locked first: two requests of one user
+// looking for a draft then queue up instead of both creating one.
+func userDraft(tx *gorm.DB, user schema.User, create bool) (schema.RulerApplication, error) {
+	var locked schema.User
+	err := forUpdate(tx).Select("id").Where("id = ?", user.Id).First(&locked).Error
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	var draft schema.RulerApplication
+	err = forUpdate(tx).
+		Where("creator_refer = ?", user.Id).
+		Where("state = ?", appState.Draft).
+		First(&draft).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) && create {
+		return createApplication(tx, user)
+	}
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	return draft, nil
+}
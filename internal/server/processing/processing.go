@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"kingdoms/internal/config"
 	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/app/policy"
 	"kingdoms/internal/server/models/responseModels"
 	"kingdoms/internal/server/models/serverModels"
 	"kingdoms/internal/server/redis"
+	"kingdoms/internal/storage"
 	"strconv"
 	"strings"
 	"time"
@@ -15,7 +18,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
-	"gorm.io/datatypes"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -23,17 +25,43 @@ import (
 const jwtPrefix = "Bearer"
 
 type Repository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cfg    *config.Config
+	files  storage.Storage
+	chains []approvalChain
 }
 
-func New(connect string) (*Repository, error) {
-	db, err := gorm.Open(postgres.Open(connect), &gorm.Config{})
+func New(connect string, cfg *config.Config) (*Repository, error) {
+	db, err := gorm.Open(postgres.Open(connect), &gorm.Config{
+		// timestamps are stored in UTC and converted to the client zone on output
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// tools such as the snapshot command pass no storage directory; they
+	// never touch attachments or charters
+	var files storage.Storage = storage.Disabled{}
+	if cfg.Attachments.Dir != "" {
+		files, err = storage.NewLocal(cfg.Attachments.Dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	chains, err := approvalChains(cfg.Approvals)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Repository{
-		db: db,
+		db:     db,
+		cfg:    cfg,
+		files:  files,
+		chains: chains,
 	}, nil
 }
 
@@ -124,14 +152,18 @@ func (r *Repository) AsyncPutApplicationInfo(applicationToPut AsyncStructApplica
 	return nil
 }
 
+// kingdomsFeedQuery holds the filters shared by the feed and the export.
+func kingdomsFeedQuery(tx *gorm.DB, kingdomName string) *gorm.DB {
+	return tx.Model(&schema.Kingdom{}).
+		Where("name LIKE ?", "%"+kingdomName+"%").
+		Where("state != 'Данные утеряны'").
+		Order("id")
+}
+
 func (r *Repository) GetKingdoms(kingdomName string) ([]schema.Kingdom, error) {
 	kingdomsToReturn := []schema.Kingdom{}
 
-	var tx *gorm.DB = r.db
-
-	err := tx.Where("name LIKE " + "'%" + kingdomName + "%'").
-		Where("state != 'Данные утеряны'").
-		Order("id").Find(&kingdomsToReturn).Error
+	err := kingdomsFeedQuery(r.db, kingdomName).Find(&kingdomsToReturn).Error
 	if err != nil {
 		return []schema.Kingdom{}, err
 	}
@@ -155,23 +187,29 @@ func (r *Repository) GetKingdom(kingdom schema.Kingdom) (schema.Kingdom, error)
 }
 
 func (r *Repository) CreateKingdom(kingdom schema.Kingdom) error {
-	err := r.db.Create(&kingdom).Error
-	if err != nil {
-		return err
-	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
 
-	return nil
+		kingdom.Slug, err = uniqueKingdomSlug(tx, kingdom.Name, 0)
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&kingdom).Error
+	})
 }
 
 func (r *Repository) UpdateKingdom(kingdom schema.Kingdom) error {
-	err := r.db.Model(&schema.Kingdom{}).
-		Where("id = ?", kingdom.Id).
-		Updates(kingdom).Error
-	if err != nil {
-		return err
-	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := renameKingdomSlug(tx, &kingdom)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		return tx.Model(&schema.Kingdom{}).
+			Where("id = ?", kingdom.Id).
+			Updates(kingdom).Error
+	})
 }
 
 func (r *Repository) UpdateKingdomStatus(kingdomToUpdate KingdomToUpdate) error {
@@ -190,13 +228,13 @@ func (r *Repository) GetDraftApplication(user schema.User) (int, error) {
 
 	var tx *gorm.DB = r.db
 
-	err := tx.Where("creator_refer = ? and state = 'В разработке'", user.Id).
+	err := tx.Where("creator_refer = ? and state = ?", user.Id, appState.Draft).
 		Find(&applicationToReturn).Error
 	if err != nil {
 		return 0, err
 	}
 
-	if applicationToReturn == (schema.RulerApplication{}) {
+	if applicationToReturn.Id == 0 {
 		return 0, nil
 	}
 
@@ -209,10 +247,15 @@ func (r *Repository) GetApplications(user schema.User, applicationId string) ([]
 	var tx *gorm.DB = r.db
 
 	if applicationId == "" {
-		err := tx.Where("creator_refer = ? and state != 'Удалена'", user.Id).
+		coAuthored := tx.Model(&schema.ApplicationCoAuthor{}).
+			Select("application_refer").
+			Where("user_refer = ? AND state = ?", user.Id, schema.CoAuthorAccepted)
+
+		err := tx.Where("(creator_refer = ? or id IN (?)) and state != ?", user.Id, coAuthored, appState.Deleted).
 			Order("id").
 			Preload("Creator").
 			Preload("Moderator").
+			Preload("CoAuthors", "state = ?", schema.CoAuthorAccepted).
 			Find(&applicationsToReturn).Error
 		if err != nil {
 			return []schema.RulerApplication{}, err
@@ -225,101 +268,46 @@ func (r *Repository) GetApplications(user schema.User, applicationId string) ([]
 		return applicationsToReturn, nil
 	}
 
-	err := tx.Where("id = ?", applicationId).Find(&applicationsToReturn).Error
+	var app schema.RulerApplication
+	err := tx.Where("id = ?", applicationId).First(&app).Error
 	if err != nil {
 		return []schema.RulerApplication{}, err
 	}
 
-	if len(applicationsToReturn) == 0 {
-		return []schema.RulerApplication{}, errors.New("no necessary ruler applications found")
+	err = loadCoAuthors(tx, &app)
+	if err != nil {
+		return []schema.RulerApplication{}, err
 	}
 
-	return applicationsToReturn, nil
-}
-
-func (r *Repository) GetAllApplications(params StructGetAllApplications) ([]schema.RulerApplication,
-	error) {
+	err = policy.Authorize(user, policy.ReadApplication, &app)
+	if err != nil {
+		return []schema.RulerApplication{}, err
+	}
 
-	var applicationsToReturn []schema.RulerApplication
-	var err error
+	return []schema.RulerApplication{app}, nil
+}
 
-	switch {
-	case params.Status == "" && params.From == datatypes.Date{} && params.To == datatypes.Date{}:
-		err = r.db.
-			Where("state != 'Удалена' AND state != 'В разработке'").
-			Order("id").
-			Preload("Creator").
-			Preload("Moderator").
-			Find(&applicationsToReturn).Error
-		break
-	case params.Status == "" && params.From == datatypes.Date{} && params.To != datatypes.Date{}:
-		err = r.db.
-			Where("state != 'Удалена' AND state != 'В разработке' AND date_send < ?", params.To).
-			Order("id").
-			Preload("Creator").
-			Preload("Moderator").
-			Find(&applicationsToReturn).Error
-		break
-	case params.Status == "" && params.From != datatypes.Date{} && params.To == datatypes.Date{}:
-		err = r.db.
-			Where("state != 'Удалена' AND state != 'В разработке' AND date_send > ?", params.From).
-			Order("id").
-			Preload("Creator").
-			Preload("Moderator").
-			Find(&applicationsToReturn).Error
-		break
-	case params.Status == "" && params.From != datatypes.Date{} && params.To != datatypes.Date{}:
-		err = r.db.
-			Where("state != 'Удалена' AND state != 'В разработке' AND date_send > ? AND date_send < ?", params.From, params.To).
-			Order("id").
-			Preload("Creator").
-			Preload("Moderator").
-			Find(&applicationsToReturn).Error
-		break
-	case params.Status != "" && params.From == datatypes.Date{} && params.To == datatypes.Date{}:
-		err = r.db.
-			Where("state = ?", params.Status).
-			Order("id").
-			Preload("Creator").
-			Preload("Moderator").
-			Find(&applicationsToReturn).Error
-		break
-	case params.Status != "" && params.From == datatypes.Date{} && params.To != datatypes.Date{}:
-		err = r.db.
-			Where("state = ? AND date_send < ?", params.Status, params.To).
-			Order("id").
-			Preload("Creator").
-			Preload("Moderator").
-			Find(&applicationsToReturn).Error
-		break
-	case params.Status != "" && params.From != datatypes.Date{} && params.To == datatypes.Date{}:
-		err = r.db.
-			Where("state = ? AND date_send > ?", params.Status, params.From).
-			Order("id").
-			Preload("Creator").
-			Preload("Moderator").
-			Find(&applicationsToReturn).Error
-		break
-	default:
-		err = r.db.
-			Where("state = ? AND date_send > ? AND date_send < ?",
-				params.Status, params.From, params.To).
-			Order("id").
-			Preload("Creator").
-			Preload("Moderator").
-			Find(&applicationsToReturn).Error
-		break
+// moderatorSubmission gives moderators the submission snapshot of an
+// application that left draft, so they decide on what was submitted rather than
+// on live kingdom data. Applications submitted before snapshots were taken
+// have none.
+func moderatorSubmission(tx *gorm.DB, user schema.User, app schema.RulerApplication) (*ApplicationSnapshotView, error) {
+	if policy.Relations(user, &app)&policy.Moderator == 0 {
+		return nil, nil
 	}
-
-	if err != nil {
-		return []schema.RulerApplication{}, err
+	if app.State != appState.Submitted && app.State != appState.Approved && app.State != appState.Rejected {
+		return nil, nil
 	}
 
-	if len(applicationsToReturn) == 0 {
-		return []schema.RulerApplication{}, errors.New("no necessary ruler applications found")
+	view, err := latestSnapshotView(tx, app)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return applicationsToReturn, nil
+	return &view, nil
 }
 
 func (r *Repository) GetApplicationWithKingdoms(user schema.User, applicationId string) (StructApplicationWithKingdoms, error) {
@@ -334,6 +322,11 @@ func (r *Repository) GetApplicationWithKingdoms(user schema.User, applicationId
 
 	var tx *gorm.DB = r.db
 
+	applicationToReturn.Submission, err = moderatorSubmission(tx, user, nestedApplication[0])
+	if err != nil {
+		return StructApplicationWithKingdoms{}, err
+	}
+
 	err = tx.Where("application_refer = ?", applicationId).Find(&kingdom2Application).Error
 	if err != nil {
 		return StructApplicationWithKingdoms{}, err
@@ -344,6 +337,11 @@ func (r *Repository) GetApplicationWithKingdoms(user schema.User, applicationId
 		return applicationToReturn, nil
 	}
 
+	attachments, err := lineAttachments(tx, applicationId)
+	if err != nil {
+		return StructApplicationWithKingdoms{}, err
+	}
+
 	for i := 0; i < len(kingdom2Application); i++ {
 		var nestedKingdom schema.Kingdom
 		err = tx.Where("id = ?", kingdom2Application[i].KingdomRefer).Find(&nestedKingdom).Error
@@ -352,9 +350,15 @@ func (r *Repository) GetApplicationWithKingdoms(user schema.User, applicationId
 		}
 
 		var kingdomFromApplication KingdomFromApplication
+		kingdomFromApplication.LineId = kingdom2Application[i].Id
 		kingdomFromApplication.Kingdom = nestedKingdom
 		kingdomFromApplication.From = kingdom2Application[i].From
 		kingdomFromApplication.To = kingdom2Application[i].To
+		kingdomFromApplication.Justification = kingdom2Application[i].Justification
+		kingdomFromApplication.Sources = lineSources(kingdom2Application[i].Sources)
+		kingdomFromApplication.Decision = kingdom2Application[i].Decision
+		kingdomFromApplication.DecisionComment = kingdom2Application[i].DecisionComment
+		kingdomFromApplication.Attachments = attachments[int(kingdom2Application[i].Id)]
 
 		applicationToReturn.Kingdoms = append(applicationToReturn.Kingdoms, kingdomFromApplication)
 	}
@@ -362,78 +366,129 @@ func (r *Repository) GetApplicationWithKingdoms(user schema.User, applicationId
 	return applicationToReturn, nil
 }
 
+// CreateApplication returns the user's draft, creating it when the user has
+// none: a user has at most one draft at a time.
 func (r *Repository) CreateApplication(user schema.User) (schema.RulerApplication, error) {
+	var application schema.RulerApplication
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+
+		application, err = userDraft(tx, user, true)
+		return err
+	})
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	return application, nil
+}
+
+// createApplication inserts a new draft. Callers hold the lock on the user's
+// row, see userDraft.
+func createApplication(tx *gorm.DB, user schema.User) (schema.RulerApplication, error) {
 	application := schema.RulerApplication{
 		Creator:        user,
 		ModeratorRefer: nil,
 		Moderator:      schema.User{},
-		State:          "В разработке",
-		DateCreate:     time.Now(),
+		State:          appState.Draft,
+		DateCreate:     time.Now().UTC(),
 	}
 
-	var tx *gorm.DB = r.db
-
 	err := tx.Create(&application).Error
 	if err != nil {
 		return schema.RulerApplication{}, err
 	}
 
+	err = recordEvent(tx, &schema.ApplicationEvent{
+		ApplicationRefer: int(application.Id),
+		ActorRefer:       int(user.Id),
+		Type:             EventCreated,
+	}, nil, application.State)
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
 	return application, nil
 }
 
 func (r *Repository) UpdateApplicationStatusUser(user schema.User,
 	applicationToUpdate ApplicationToUpdate) (AsyncStructApplication, error) {
 
-	var tx *gorm.DB = r.db
-
-	var app schema.RulerApplication
-	err := tx.Model(&schema.RulerApplication{}).
-		Where("id = ?", applicationToUpdate.Id).
-		Where("creator_refer = ?", user.Id).
-		First(&app).Error
+	target, err := appState.Parse(applicationToUpdate.State)
 	if err != nil {
 		return AsyncStructApplication{}, err
 	}
-	if app == (schema.RulerApplication{}) {
-		return AsyncStructApplication{}, errors.New("no necessary application found")
-	}
 
-	switch applicationToUpdate.State {
-	case "Удалена":
-		err = tx.Model(&schema.RulerApplication{}).
-			Where("id = ?", applicationToUpdate.Id).
-			Where("creator_refer = ?", user.Id).
-			Update("state", applicationToUpdate.State).Error
+	var applicationToReturn AsyncStructApplication
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := lockedApplication(tx, applicationToUpdate.Id)
 		if err != nil {
-			return AsyncStructApplication{}, err
+			return err
 		}
-		break
 
-	default:
-		err = tx.Model(&schema.RulerApplication{}).
-			Where("id = ?", applicationToUpdate.Id).
-			Where("creator_refer = ?", user.Id).
-			Updates(map[string]interface{}{
-				"state":     applicationToUpdate.State,
-				"date_send": time.Now(),
-			}).Error
+		err = policy.Authorize(user, policy.SubmitApplication, &app)
 		if err != nil {
-			return AsyncStructApplication{}, err
+			return err
 		}
-		break
-	}
 
-	var applicationToReturn AsyncStructApplication
-	err = tx.Table("ruler_applications").
-		Select("id, 'check'").
-		Where("id = ?", applicationToUpdate.Id).
-		Where("creator_refer = ?", user.Id).
-		Scan(&applicationToReturn).Error
+		transition, err := appState.Check(app.State, target, user.Role, true)
+		if err != nil {
+			return err
+		}
+		if transition.Restores {
+			return ErrRestoreFromTrash
+		}
+
+		updates := map[string]interface{}{
+			"state": transition.To,
+		}
+		if transition.SetsDateSend {
+			updates["date_send"] = time.Now().UTC()
+		}
+		if transition.To == appState.Deleted {
+			trashUpdates(app, updates)
+		}
+
+		err = applyTransition(tx, app, updates)
+		if err != nil {
+			return err
+		}
+
+		if transition.To == appState.Submitted {
+			err = takeSubmissionSnapshot(tx, app)
+			if err != nil {
+				return err
+			}
+		}
+
+		event := schema.ApplicationEvent{
+			ApplicationRefer: int(app.Id),
+			ActorRefer:       int(user.Id),
+			Type:             EventStateChange,
+			Comment:          applicationToUpdate.Comment,
+		}
+		err = recordEvent(tx, &event, app.State, transition.To)
+		if err != nil {
+			return err
+		}
+
+		err = addDecisionComment(tx, event, nil)
+		if err != nil {
+			return err
+		}
 
-	err = tx.Model(&schema.RulerApplication{}).
-		Where("id = ?", applicationToUpdate.Id).
-		Where("creator_refer = ?", user.Id).
-		First(&applicationToReturn).Error
+		applicationToReturn = AsyncStructApplication{
+			Id:    app.Id,
+			Check: app.Check,
+		}
+
+		return nil
+	})
+	if err != nil {
+		return AsyncStructApplication{}, err
+	}
 
 	return applicationToReturn, nil
 }
@@ -441,87 +496,214 @@ func (r *Repository) UpdateApplicationStatusUser(user schema.User,
 func (r *Repository) UpdateApplicationStatusModerator(user schema.User,
 	applicationToUpdate ApplicationToUpdate) error {
 
-	var tx *gorm.DB = r.db
+	target, err := appState.Parse(applicationToUpdate.State)
+	if err != nil {
+		return err
+	}
 
-	var app schema.RulerApplication
-	err := tx.Model(&schema.RulerApplication{}).
-		Where("id = ?", applicationToUpdate.Id).
-		First(&app).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return r.applyModeratorDecision(tx, user, applicationToUpdate.Id, target, applicationToUpdate.Comment)
+	})
+}
+
+// applyModeratorDecision moves one application to target on behalf of a
+// moderator, recording the event and the decision comment. An approval signs
+// off the next stage of the approval chain and moves the application only
+// once the last stage is signed.
+func (r *Repository) applyModeratorDecision(tx *gorm.DB, user schema.User, applicationId uint, target appState.State,
+	comment string) error {
+
+	app, err := lockedApplication(tx, applicationId)
+	if err != nil {
+		return err
+	}
+
+	err = policy.Authorize(user, policy.DecideApplication, &app)
+	if err != nil {
+		return err
+	}
+
+	transition, err := appState.Check(app.State, target, user.Role, false)
 	if err != nil {
 		return err
 	}
-	if app == (schema.RulerApplication{}) {
-		return errors.New("no necessary application found")
+	if transition.Restores {
+		return ErrRestoreFromTrash
+	}
+
+	if transition.To == appState.Approved {
+		approval, final, err := r.signApproval(tx, user, app, comment)
+		if err != nil {
+			return err
+		}
+
+		if !final {
+			event := schema.ApplicationEvent{
+				ApplicationRefer: int(app.Id),
+				ActorRefer:       int(user.Id),
+				Type:             EventApprovalStage,
+				Comment:          comment,
+			}
+			err = recordEvent(tx, &event, nil, approval.StageName)
+			if err != nil {
+				return err
+			}
+
+			return addDecisionComment(tx, event, nil)
+		}
+
+		err = pruneRejectedLines(tx, user, app)
+		if err != nil {
+			return err
+		}
+
+		err = r.checkApprovedOverlaps(tx, app)
+		if err != nil {
+			return err
+		}
+	}
+
+	updates := map[string]interface{}{
+		"state":           transition.To,
+		"moderator_refer": user.Id,
+	}
+	if transition.SetsDateComplete {
+		updates["date_complete"] = time.Now().UTC()
 	}
 
-	err = tx.Model(&schema.RulerApplication{}).
-		Where("id = ?", applicationToUpdate.Id).
-		Updates(map[string]interface{}{
-			"state":           applicationToUpdate.State,
-			"date_complete":   time.Now(),
-			"moderator_refer": user.Id,
-		}).Error
+	err = applyTransition(tx, app, updates)
 	if err != nil {
 		return err
 	}
 
+	if transition.To == appState.Submitted || transition.To == appState.Rejected {
+		err = revokeApprovals(tx, app.Id)
+		if err != nil {
+			return err
+		}
+	}
+	if app.State == appState.Approved {
+		err = revokeCharters(tx, app.Id)
+		if err != nil {
+			return err
+		}
+	}
+	if transition.To == appState.Approved {
+		_, err = issueCharter(tx, app.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	event := schema.ApplicationEvent{
+		ApplicationRefer: int(app.Id),
+		ActorRefer:       int(user.Id),
+		Type:             EventStateChange,
+		Comment:          comment,
+	}
+	err = recordEvent(tx, &event, app.State, transition.To)
+	if err != nil {
+		return err
+	}
+
+	return addDecisionComment(tx, event, nil)
+}
+
+// applyTransition moves the application out of the state it was read in. If
+// someone changed the state in between, the transition is no longer legal.
+func applyTransition(tx *gorm.DB, app schema.RulerApplication, updates map[string]interface{}) error {
+	res := tx.Model(&schema.RulerApplication{}).
+		Where("id = ? AND state = ?", app.Id, app.State).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return appState.ErrIllegalTransition
+	}
+
 	return nil
 }
 
 func (r *Repository) UpdateApplication(user schema.User,
 	applicationToUpdate schema.RulerApplication) error {
 
-	var tx *gorm.DB = r.db
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := editableApplication(tx, user, applicationToUpdate.Id)
+		if err != nil {
+			return err
+		}
 
-	err := tx.Model(&schema.RulerApplication{}).
-		Where("id = ?", applicationToUpdate.Id).
-		Update("ruler", applicationToUpdate.Ruler).Error
-	if err != nil {
-		return err
-	}
+		err = tx.Model(&schema.RulerApplication{}).
+			Where("id = ?", app.Id).
+			Update("ruler", applicationToUpdate.Ruler).Error
+		if err != nil {
+			return err
+		}
 
-	return nil
+		err = touchApplication(tx, app.Id)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: int(app.Id),
+			ActorRefer:       int(user.Id),
+			Type:             EventRulerUpdate,
+		}, app.Ruler, applicationToUpdate.Ruler)
+	})
 }
 
+// AddKingdomToApplication adds a kingdom line to the draft given by id, or to
+// the user's own draft, creating it first when the user has none.
 func (r *Repository) AddKingdomToApplication(user schema.User,
 	kingdomAddToApplication KingdomAddToApplication) (StructApplicationWithKingdoms, error) {
-	var tx *gorm.DB = r.db
 
-	var draft schema.RulerApplication
-	err := tx.Model(&schema.RulerApplication{}).
-		Where("creator_refer = ?", user.Id).
-		Where("state = 'В разработке'").
-		First(&draft).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := draftToEdit(tx, user, kingdomAddToApplication.ApplicationId, true)
+		if err != nil {
+			return err
+		}
+
+		kingdomAddToApplication.ApplicationId = app.Id
 
-	if draft == (schema.RulerApplication{}) {
-		draft, err = r.CreateApplication(user)
+		justification, sources, err := lineEvidence(kingdomAddToApplication)
 		if err != nil {
-			return StructApplicationWithKingdoms{}, err
+			return err
 		}
-	}
 
-	kingdomAddToApplication.ApplicationId = draft.Id
+		var kingdom2Application = schema.Kingdom2Application{
+			ApplicationRefer: int(app.Id),
+			KingdomRefer:     int(kingdomAddToApplication.KingdomId),
+			From:             kingdomAddToApplication.From,
+			To:               kingdomAddToApplication.To,
+			Justification:    justification,
+			Sources:          sources,
+			Decision:         LinePending,
+		}
 
-	var app schema.RulerApplication
-	err = tx.Model(&schema.RulerApplication{}).
-		Where("id = ?", kingdomAddToApplication.ApplicationId).
-		Where("creator_refer = ?", user.Id).
-		First(&app).Error
-	if err != nil {
-		return StructApplicationWithKingdoms{}, err
-	}
-	if app == (schema.RulerApplication{}) {
-		return StructApplicationWithKingdoms{}, errors.New("no necessary application found")
-	}
+		err = r.checkKingdomPeriod(tx, kingdom2Application)
+		if err != nil {
+			return err
+		}
 
-	var kingdom2Application = schema.Kingdom2Application{
-		ApplicationRefer: int(kingdomAddToApplication.ApplicationId),
-		KingdomRefer:     int(kingdomAddToApplication.KingdomId),
-		From:             kingdomAddToApplication.From,
-		To:               kingdomAddToApplication.To,
-	}
+		err = tx.Create(&kingdom2Application).Error
+		if err != nil {
+			return err
+		}
+
+		err = touchApplication(tx, app.Id)
+		if err != nil {
+			return err
+		}
 
-	err = r.db.Create(&kingdom2Application).Error
+		return recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: kingdom2Application.ApplicationRefer,
+			ActorRefer:       int(user.Id),
+			Type:             EventKingdomAdd,
+			KingdomRefer:     &kingdom2Application.KingdomRefer,
+		}, nil, kingdomPeriod{From: kingdom2Application.From, To: kingdom2Application.To})
+	})
 	if err != nil {
 		return StructApplicationWithKingdoms{}, err
 	}
@@ -531,44 +713,72 @@ func (r *Repository) AddKingdomToApplication(user schema.User,
 	if err != nil {
 		return StructApplicationWithKingdoms{}, err
 	}
+
 	return applicationToReturn, nil
 }
 
 func (r *Repository) UpdateKingdomFromApplication(user schema.User,
 	kingdomAddToApplication KingdomAddToApplication) (StructApplicationWithKingdoms, error) {
-	var tx *gorm.DB = r.db
 
-	var draft schema.RulerApplication
-	err := tx.Model(&schema.RulerApplication{}).
-		Where("creator_refer = ?", user.Id).
-		Where("state = 'В разработке'").
-		First(&draft).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := draftToEdit(tx, user, kingdomAddToApplication.ApplicationId, false)
+		if err != nil {
+			return err
+		}
 
-	kingdomAddToApplication.ApplicationId = draft.Id
+		kingdomAddToApplication.ApplicationId = app.Id
 
-	var app schema.RulerApplication
-	err = tx.Model(&schema.RulerApplication{}).
-		Where("id = ?", kingdomAddToApplication.ApplicationId).
-		Where("creator_refer = ?", user.Id).
-		First(&app).Error
-	if err != nil {
-		return StructApplicationWithKingdoms{}, err
-	}
-	if app == (schema.RulerApplication{}) {
-		return StructApplicationWithKingdoms{}, errors.New("no necessary application found")
-	}
+		justification, sources, err := lineEvidence(kingdomAddToApplication)
+		if err != nil {
+			return err
+		}
 
-	var kingdom2Application = schema.Kingdom2Application{
-		ApplicationRefer: int(kingdomAddToApplication.ApplicationId),
-		KingdomRefer:     int(kingdomAddToApplication.KingdomId),
-		From:             kingdomAddToApplication.From,
-		To:               kingdomAddToApplication.To,
-	}
+		var kingdom2Application = schema.Kingdom2Application{
+			ApplicationRefer: int(app.Id),
+			KingdomRefer:     int(kingdomAddToApplication.KingdomId),
+			From:             kingdomAddToApplication.From,
+			To:               kingdomAddToApplication.To,
+			Justification:    justification,
+			Sources:          sources,
+			Decision:         LinePending,
+		}
 
-	err = r.db.Model(&schema.Kingdom2Application{}).
-		Where("application_refer = ? AND kingdom_refer = ?",
-			kingdom2Application.ApplicationRefer, kingdom2Application.KingdomRefer).
-		Updates(kingdom2Application).Error
+		var existing schema.Kingdom2Application
+		err = forUpdate(tx).
+			Where("application_refer = ? AND kingdom_refer = ?",
+				kingdom2Application.ApplicationRefer, kingdom2Application.KingdomRefer).
+			First(&existing).Error
+		if err != nil {
+			return err
+		}
+
+		err = r.checkKingdomPeriod(tx, kingdom2Application)
+		if err != nil {
+			return err
+		}
+
+		// a changed line is no longer the one a moderator decided on
+		err = tx.Model(&schema.Kingdom2Application{}).
+			Where("id = ?", existing.Id).
+			Select("from", "to", "justification", "sources", "decision", "decision_comment", "decider_refer").
+			Updates(kingdom2Application).Error
+		if err != nil {
+			return err
+		}
+
+		err = touchApplication(tx, app.Id)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: kingdom2Application.ApplicationRefer,
+			ActorRefer:       int(user.Id),
+			Type:             EventKingdomUpdate,
+			KingdomRefer:     &kingdom2Application.KingdomRefer,
+		}, kingdomPeriod{From: existing.From, To: existing.To},
+			kingdomPeriod{From: kingdom2Application.From, To: kingdom2Application.To})
+	})
 	if err != nil {
 		return StructApplicationWithKingdoms{}, err
 	}
@@ -585,45 +795,93 @@ func (r *Repository) UpdateKingdomFromApplication(user schema.User,
 func (r *Repository) DeleteKingdomFromApplication(user schema.User,
 	kingdomToDeleteFromApplication DeleteKingdomFromApplication) error {
 
-	var tx *gorm.DB = r.db
-
-	var app schema.RulerApplication
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := editableApplication(tx, user, kingdomToDeleteFromApplication.ApplicationId)
+		if err != nil {
+			return err
+		}
 
-	err := tx.Model(&schema.RulerApplication{}).
-		Where("id = ?", kingdomToDeleteFromApplication.ApplicationId).
-		Where("creator_refer = ?", user.Id).
-		First(&app).Error
-	if err != nil {
-		return err
-	}
-	if app == (schema.RulerApplication{}) {
-		return errors.New("no necessary application found")
-	}
+		var existing schema.Kingdom2Application
+		err = forUpdate(tx).
+			Where("application_refer = ? AND kingdom_refer = ?",
+				app.Id, kingdomToDeleteFromApplication.KingdomId).
+			First(&existing).Error
+		if err != nil {
+			return err
+		}
 
-	var kingdom2Application = schema.Kingdom2Application{
-		ApplicationRefer: int(kingdomToDeleteFromApplication.ApplicationId),
-		KingdomRefer:     int(kingdomToDeleteFromApplication.KingdomId),
-	}
+		err = tx.Delete(&existing).Error
+		if err != nil {
+			return err
+		}
 
-	err = r.db.Where("application_refer = ? AND kingdom_refer = ?",
-		kingdom2Application.ApplicationRefer, kingdom2Application.KingdomRefer).
-		Delete(&kingdom2Application).Error
-	if err != nil {
-		return err
-	}
+		err = touchApplication(tx, app.Id)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		return recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: existing.ApplicationRefer,
+			ActorRefer:       int(user.Id),
+			Type:             EventKingdomRemove,
+			KingdomRefer:     &existing.KingdomRefer,
+		}, kingdomPeriod{From: existing.From, To: existing.To}, nil)
+	})
 }
 
+// DeleteApplication moves the application to the trash. Owners delete what the
+// state table lets them, their drafts; admins may trash an application in any
+// state, which voids its approvals and charters. The purge removes it for good
+// once the retention expires.
 func (r *Repository) DeleteApplication(user schema.User, applicationToDelete schema.RulerApplication) error {
-	var tx *gorm.DB = r.db
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := lockedApplication(tx, applicationToDelete.Id)
+		if err != nil {
+			return err
+		}
 
-	err := tx.Where("id = ?", applicationToDelete.Id).Delete(&schema.RulerApplication{}).Error
-	if err != nil {
-		return err
-	}
+		err = policy.Authorize(user, policy.DeleteApplication, &app)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		if app.State == appState.Deleted {
+			return appState.ErrIllegalTransition
+		}
+
+		if policy.Relations(user, &app)&policy.Admin == 0 {
+			_, err = appState.Check(app.State, appState.Deleted, user.Role, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		updates := map[string]interface{}{
+			"state": appState.Deleted,
+		}
+		trashUpdates(app, updates)
+
+		err = applyTransition(tx, app, updates)
+		if err != nil {
+			return err
+		}
+
+		err = revokeApprovals(tx, app.Id)
+		if err != nil {
+			return err
+		}
+
+		err = revokeCharters(tx, app.Id)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: int(app.Id),
+			ActorRefer:       int(user.Id),
+			Type:             EventStateChange,
+		}, app.State, appState.Deleted)
+	})
 }
 
 // func (r *Repository) GetUserApplicationsWithKingdoms(user schema.User, applicationId string) (StructApplicationWithKingdoms, error) {
@@ -822,3 +1080,9 @@ func (r *Repository) GetUserByName(name string) (*schema.User, error) {
 
 	return user, nil
 }
+
+func (r *Repository) UpdateUserTimeZone(user schema.User, timeZone string) error {
+	return r.db.Model(&schema.User{}).
+		Where("id = ?", user.Id).
+		Update("time_zone", timeZone).Error
+}
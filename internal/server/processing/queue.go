@@ -0,0 +1,35 @@
+package processing
+
+import (
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/app/policy"
+
+	"gorm.io/gorm"
+)
+
+// GetQueuedApplicationIds returns submitted applications waiting for a
+// decision, oldest submission first. Applications user already signed a stage
+// of wait for other moderators and are left out.
+func (r *Repository) GetQueuedApplicationIds(user schema.User) ([]uint, error) {
+	var tx *gorm.DB = r.db
+
+	err := policy.Authorize(user, policy.DecideApplication, nil)
+	if err != nil {
+		return []uint{}, err
+	}
+
+	ids := []uint{}
+	err = tx.Model(&schema.RulerApplication{}).
+		Where("state = ?", appState.Submitted).
+		Where(`NOT EXISTS (SELECT 1 FROM application_approvals
+			WHERE application_approvals.application_refer = ruler_applications.id
+			AND application_approvals.approver_refer = ? AND application_approvals.date_revoked IS NULL)`, user.Id).
+		Order("date_send, id").
+		Pluck("id", &ids).Error
+	if err != nil {
+		return []uint{}, err
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,159 @@
+package processing
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kingdoms/internal/config"
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	role "kingdoms/internal/server/app/userRole"
+	"kingdoms/internal/storage"
+
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testRepository returns a repository on an empty SQLite database with the
+// application tables. SQLite ignores the row locks the repository takes, so
+// tests see what a single request does, not how concurrent ones interleave.
+func testRepository(t *testing.T, chains ...approvalChain) *Repository {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "kingdoms.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	models := []interface{}{
+		&schema.Kingdom{},
+		&schema.User{},
+		&schema.RulerApplication{},
+		&schema.Kingdom2Application{},
+		&schema.KingdomTranslation{},
+		&schema.ApplicationEvent{},
+		&schema.ApplicationComment{},
+		&schema.ApplicationSnapshot{},
+		&schema.PurgeLog{},
+		&schema.Notification{},
+		&schema.Attachment{},
+		&schema.Charter{},
+		&schema.ApplicationApproval{},
+		&schema.ApplicationCoAuthor{},
+	}
+	for _, model := range models {
+		// SQLite has no now(); its CURRENT_TIMESTAMP is the same default
+		stmt := &gorm.Statement{DB: db}
+		err = stmt.Parse(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DefaultValue == "now()" {
+				field.DefaultValue = "CURRENT_TIMESTAMP"
+			}
+		}
+
+		err = db.AutoMigrate(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &Repository{
+		db:     db,
+		cfg:    &config.Config{},
+		files:  storage.Disabled{},
+		chains: chains,
+	}
+}
+
+func testUser(t *testing.T, r *Repository, name string, userRole role.Role) schema.User {
+	t.Helper()
+
+	user := schema.User{Name: name, Role: userRole}
+	err := r.db.Create(&user).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+func testKingdom(t *testing.T, r *Repository, name string, area int) schema.Kingdom {
+	t.Helper()
+
+	kingdom := schema.Kingdom{Name: name, Area: area, Capital: name, State: "Данные подтверждены", Slug: name}
+	err := r.db.Create(&kingdom).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return kingdom
+}
+
+// testApplication creates an application of creator in state, claiming each
+// of the kingdoms for the year 1132.
+func testApplication(t *testing.T, r *Repository, creator schema.User, state appState.State,
+	kingdoms ...schema.Kingdom) schema.RulerApplication {
+	t.Helper()
+
+	app := schema.RulerApplication{State: state, Ruler: "Мстислав Владимирович", CreatorRefer: int(creator.Id)}
+	err := r.db.Create(&app).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kingdom := range kingdoms {
+		testLine(t, r, app, kingdom, date(1132, 1, 1), date(1132, 12, 31))
+	}
+
+	return app
+}
+
+func testLine(t *testing.T, r *Repository, app schema.RulerApplication, kingdom schema.Kingdom,
+	from time.Time, to time.Time) schema.Kingdom2Application {
+	t.Helper()
+
+	line := schema.Kingdom2Application{
+		KingdomRefer:     int(kingdom.Id),
+		ApplicationRefer: int(app.Id),
+		From:             datatypes.Date(from),
+		To:               datatypes.Date(to),
+		Decision:         LinePending,
+	}
+	err := r.db.Create(&line).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return line
+}
+
+func applicationState(t *testing.T, r *Repository, applicationId uint) appState.State {
+	t.Helper()
+
+	var app schema.RulerApplication
+	err := r.db.Select("state").Where("id = ?", applicationId).First(&app).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return app.State
+}
+
+// testDecision runs a moderator decision in its own transaction, as the
+// handlers do.
+func testDecision(r *Repository, user schema.User, applicationId uint, target appState.State) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return r.applyModeratorDecision(tx, user, applicationId, target, "")
+	})
+}
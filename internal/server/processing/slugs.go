@@ -0,0 +1,109 @@
+package processing
+
+import (
+	"errors"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/database/slug"
+
+	"gorm.io/gorm"
+)
+
+// uniqueKingdomSlug makes a slug for name that is neither the current nor a
+// former slug of any kingdom other than kingdomId.
+func uniqueKingdomSlug(tx *gorm.DB, name string, kingdomId uint) (string, error) {
+	return slug.Unique(slug.Make(name), func(candidate string) (bool, error) {
+		var count int64
+
+		err := tx.Model(&schema.Kingdom{}).
+			Where("slug = ? AND id != ?", candidate, kingdomId).
+			Count(&count).Error
+		if err != nil || count != 0 {
+			return true, err
+		}
+
+		err = tx.Model(&schema.KingdomSlug{}).
+			Where("slug = ? AND kingdom_refer != ?", candidate, kingdomId).
+			Count(&count).Error
+		if err != nil {
+			return true, err
+		}
+
+		return count != 0, nil
+	})
+}
+
+// renameKingdomSlug gives the kingdom a slug for its new name and keeps the
+// previous one in the history table.
+func renameKingdomSlug(tx *gorm.DB, kingdom *schema.Kingdom) error {
+	var current schema.Kingdom
+	err := tx.Select("id, name, slug").Where("id = ?", kingdom.Id).First(&current).Error
+	if err != nil {
+		return err
+	}
+
+	if kingdom.Name == "" || kingdom.Name == current.Name && current.Slug != "" {
+		kingdom.Slug = ""
+		return nil
+	}
+
+	newSlug, err := uniqueKingdomSlug(tx, kingdom.Name, kingdom.Id)
+	if err != nil {
+		return err
+	}
+
+	if newSlug == current.Slug {
+		kingdom.Slug = ""
+		return nil
+	}
+
+	err = tx.Where("kingdom_refer = ? AND slug = ?", kingdom.Id, newSlug).
+		Delete(&schema.KingdomSlug{}).Error
+	if err != nil {
+		return err
+	}
+
+	if current.Slug != "" {
+		err = tx.Create(&schema.KingdomSlug{
+			KingdomRefer: int(kingdom.Id),
+			Slug:         current.Slug,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	kingdom.Slug = newSlug
+
+	return nil
+}
+
+// GetKingdomBySlug looks the kingdom up by its current slug. When slugStr is a
+// former slug, the kingdom is returned together with its current slug so that
+// the caller can redirect.
+func (r *Repository) GetKingdomBySlug(slugStr string) (schema.Kingdom, string, error) {
+	var kingdomToReturn schema.Kingdom
+
+	var tx *gorm.DB = r.db
+
+	err := tx.Where("slug = ?", slugStr).First(&kingdomToReturn).Error
+	if err == nil {
+		return kingdomToReturn, "", nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return schema.Kingdom{}, "", err
+	}
+
+	var former schema.KingdomSlug
+	err = tx.Where("slug = ?", slugStr).First(&former).Error
+	if err != nil {
+		return schema.Kingdom{}, "", err
+	}
+
+	err = tx.Where("id = ?", former.KingdomRefer).First(&kingdomToReturn).Error
+	if err != nil {
+		return schema.Kingdom{}, "", err
+	}
+
+	return kingdomToReturn, kingdomToReturn.Slug, nil
+}
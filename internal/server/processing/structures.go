@@ -1,7 +1,11 @@
 package processing
 
 import (
+	"io"
+	"time"
+
 	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
 
 	"gorm.io/datatypes"
 )
@@ -9,12 +13,21 @@ import (
 type StructApplicationWithKingdoms struct {
 	Application schema.RulerApplication
 	Kingdoms    []KingdomFromApplication
+	// Submission shows moderators what was submitted and how the live data
+	// above has changed since; it is left out for drafts and other users
+	Submission *ApplicationSnapshotView `json:",omitempty"`
 }
 
 type KingdomFromApplication struct {
-	Kingdom schema.Kingdom
-	From    datatypes.Date
-	To      datatypes.Date
+	LineId          uint
+	Kingdom         schema.Kingdom
+	From            datatypes.Date
+	To              datatypes.Date
+	Justification   string
+	Sources         []string
+	Decision        string
+	DecisionComment string
+	Attachments     []schema.Attachment
 }
 
 type AsyncStructApplication struct {
@@ -23,8 +36,9 @@ type AsyncStructApplication struct {
 }
 
 type ApplicationToUpdate struct {
-	Id    uint
-	State string
+	Id      uint
+	State   string
+	Comment string
 }
 
 type KingdomAddToApplication struct {
@@ -32,6 +46,22 @@ type KingdomAddToApplication struct {
 	KingdomId     uint
 	From          datatypes.Date
 	To            datatypes.Date
+	Justification string
+	Sources       []string
+}
+
+// LineDecision is a moderator's verdict on one kingdom line.
+type LineDecision struct {
+	Decision string
+	Comment  string
+}
+
+// AttachmentUpload is a file uploaded to the application, about one of its
+// lines when LineId is set.
+type AttachmentUpload struct {
+	LineId  uint
+	Name    string
+	Content io.Reader
 }
 
 type DeleteKingdomFromApplication struct {
@@ -39,13 +69,242 @@ type DeleteKingdomFromApplication struct {
 	KingdomId     uint
 }
 
+// StructGetAllApplications filters, sorts and pages the application list.
+// Zero values leave a filter out; the To bounds are exclusive.
 type StructGetAllApplications struct {
-	Status string
-	From   datatypes.Date
-	To     datatypes.Date
+	States        []appState.State
+	Creator       uint
+	Moderator     uint
+	Kingdom       uint
+	Check         *bool
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	SentFrom      time.Time
+	SentTo        time.Time
+	CompletedFrom time.Time
+	CompletedTo   time.Time
+	Ruler         string
+
+	Sort   string
+	Desc   bool
+	Limit  int
+	Cursor string
+}
+
+type ApplicationsPage struct {
+	Applications []schema.RulerApplication
+	NextCursor   string `json:",omitempty"`
 }
 
 type KingdomToUpdate struct {
 	Id    uint
 	State string
 }
+
+type KingdomTranslationToUpdate struct {
+	KingdomId   uint
+	Locale      string
+	Name        string
+	Capital     string
+	Description string
+}
+
+type MissingTranslation struct {
+	KingdomId   uint
+	KingdomName string
+	Locale      string
+	Fields      []string
+}
+
+type KingdomImportRow struct {
+	Row     int
+	Kingdom schema.Kingdom
+	Error   string
+}
+
+type KingdomImportResult struct {
+	Row    int
+	Name   string
+	Result string
+	Reason string `json:",omitempty"`
+}
+
+type KingdomImportReport struct {
+	DryRun    bool
+	Committed bool
+	Created   int
+	Updated   int
+	Skipped   int
+	Errors    int
+	Rows      []KingdomImportResult
+}
+
+type CommentToUpdate struct {
+	Text   string
+	LineId uint
+}
+
+type ClaimConflict struct {
+	KingdomId           uint
+	KingdomName         string
+	FirstApplicationId  uint
+	FirstState          appState.State
+	FirstFrom           datatypes.Date
+	FirstTo             datatypes.Date
+	SecondApplicationId uint
+	SecondState         appState.State
+	SecondFrom          datatypes.Date
+	SecondTo            datatypes.Date
+}
+
+type ClaimedPeriod struct {
+	ApplicationId uint
+	State         appState.State
+	From          datatypes.Date
+	To            datatypes.Date
+}
+
+type FreePeriod struct {
+	From datatypes.Date
+	To   datatypes.Date
+}
+
+type KingdomAvailability struct {
+	KingdomId  uint
+	From       datatypes.Date
+	To         datatypes.Date
+	Claimed    []ClaimedPeriod
+	Free       []FreePeriod
+	Suggestion *FreePeriod `json:",omitempty"`
+}
+
+type BulkDecision struct {
+	Ids     []uint
+	State   string
+	Comment string
+}
+
+type DecisionResult struct {
+	Id  uint
+	Err error `json:"-"`
+}
+
+type TimeZoneToUpdate struct {
+	TimeZone string
+}
+
+type SubmittedKingdom struct {
+	LineId    uint
+	KingdomId uint
+	Name      string
+	Area      int
+	Capital   string
+	From      datatypes.Date
+	To        datatypes.Date
+}
+
+type SubmittedApplication struct {
+	Ruler    string
+	Kingdoms []SubmittedKingdom
+}
+
+type SnapshotChange struct {
+	LineId    uint `json:",omitempty"`
+	KingdomId uint `json:",omitempty"`
+	Field     string
+	Submitted interface{}
+	Current   interface{}
+}
+
+type ApplicationSnapshotView struct {
+	ApplicationId uint
+	DateCreate    time.Time
+	Snapshot      SubmittedApplication
+	Changed       bool
+	Changes       []SnapshotChange
+}
+
+type CloneApplication struct {
+	ShiftDays int    // moves every period by this many days, may be negative
+	Mode      string // CloneFail or CloneMerge, what to do when the caller already has a draft
+}
+
+type SkippedLine struct {
+	KingdomId uint
+	Reason    string
+}
+
+type ClonedApplication struct {
+	Application StructApplicationWithKingdoms
+	Skipped     []SkippedLine
+}
+
+type DraftExpiry struct {
+	ApplicationId uint
+	CreatorId     uint
+	CreatorName   string
+	Ruler         string
+	LastModified  time.Time
+	DateWarned    *time.Time
+	ExpiresAt     time.Time
+}
+
+type IdleDrafts struct {
+	Days  int
+	Count int64
+}
+
+type DraftsOverview struct {
+	Total    int64
+	Warned   int64
+	Idle     []IdleDrafts
+	Upcoming []DraftExpiry
+}
+
+type DraftExpiryRun struct {
+	Warned  []uint
+	Expired []uint
+}
+
+// CharterVerification is what the public verification page shows for a
+// charter code.
+type CharterVerification struct {
+	Code         string
+	Valid        bool
+	Ruler        string           `json:",omitempty"`
+	Kingdoms     []CharterKingdom `json:",omitempty"`
+	Moderator    string           `json:",omitempty"`
+	DecisionDate *time.Time       `json:",omitempty"`
+	DateRevoked  *time.Time       `json:",omitempty"`
+}
+
+// CharterKingdom is a kingdom granted by a charter. Its period is made of
+// dates, which read the same in every time zone.
+type CharterKingdom struct {
+	Name string
+	From datatypes.Date
+	To   datatypes.Date
+}
+
+// ApprovalProgress is the approval chain of an application with the stages
+// signed off so far.
+type ApprovalProgress struct {
+	Chain    string
+	Complete bool
+	Stages   []StageProgress
+}
+
+type StageProgress struct {
+	Stage    int
+	Name     string
+	Role     string
+	Approval *schema.ApplicationApproval `json:",omitempty"`
+}
+
+type CoAuthorInvitation struct {
+	UserName string
+}
+
+type InvitationAnswer struct {
+	Accept bool
+}
@@ -0,0 +1,211 @@
+package processing
+
+import (
+	"encoding/json"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/policy"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	ChangeRuler   = "ruler"
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeName    = "name"
+	ChangeArea    = "area"
+	ChangeCapital = "capital"
+	ChangeFrom    = "from"
+	ChangeTo      = "to"
+)
+
+// submittedApplication reads the application as it is now in the shape
+// snapshots are stored in.
+func submittedApplication(tx *gorm.DB, app schema.RulerApplication) (SubmittedApplication, error) {
+	var lines []schema.Kingdom2Application
+	err := tx.Where("application_refer = ?", app.Id).
+		Order("id").
+		Preload("Kingdom").
+		Find(&lines).Error
+	if err != nil {
+		return SubmittedApplication{}, err
+	}
+
+	submitted := SubmittedApplication{
+		Ruler:    app.Ruler,
+		Kingdoms: []SubmittedKingdom{},
+	}
+	for _, line := range lines {
+		submitted.Kingdoms = append(submitted.Kingdoms, SubmittedKingdom{
+			LineId:    line.Id,
+			KingdomId: line.Kingdom.Id,
+			Name:      line.Kingdom.Name,
+			Area:      line.Kingdom.Area,
+			Capital:   line.Kingdom.Capital,
+			From:      line.From,
+			To:        line.To,
+		})
+	}
+
+	return submitted, nil
+}
+
+// takeSubmissionSnapshot freezes the application as it leaves draft. Callers
+// hold the lock on the application row, so its lines cannot change meanwhile.
+func takeSubmissionSnapshot(tx *gorm.DB, app schema.RulerApplication) error {
+	submitted, err := submittedApplication(tx, app)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(submitted)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&schema.ApplicationSnapshot{
+		ApplicationRefer: int(app.Id),
+		Data:             data,
+	}).Error
+}
+
+// sameSubmittedValue compares dates as instants: a date read back from JSON
+// and one read from the database may differ in location only.
+func sameSubmittedValue(was interface{}, now interface{}) bool {
+	wasDate, ok := was.(datatypes.Date)
+	if !ok {
+		return was == now
+	}
+
+	return time.Time(wasDate).Equal(time.Time(now.(datatypes.Date)))
+}
+
+// diffSubmission lists what changed in live data since the snapshot was taken.
+func diffSubmission(submitted SubmittedApplication, current SubmittedApplication) []SnapshotChange {
+	changes := []SnapshotChange{}
+
+	if submitted.Ruler != current.Ruler {
+		changes = append(changes, SnapshotChange{
+			Field:     ChangeRuler,
+			Submitted: submitted.Ruler,
+			Current:   current.Ruler,
+		})
+	}
+
+	currentLines := make(map[uint]SubmittedKingdom, len(current.Kingdoms))
+	for _, kingdom := range current.Kingdoms {
+		currentLines[kingdom.LineId] = kingdom
+	}
+
+	for _, was := range submitted.Kingdoms {
+		now, ok := currentLines[was.LineId]
+		if !ok {
+			changes = append(changes, SnapshotChange{
+				LineId:    was.LineId,
+				KingdomId: was.KingdomId,
+				Field:     ChangeRemoved,
+				Submitted: was,
+			})
+			continue
+		}
+		delete(currentLines, was.LineId)
+
+		fields := []struct {
+			name     string
+			was, now interface{}
+		}{
+			{ChangeName, was.Name, now.Name},
+			{ChangeArea, was.Area, now.Area},
+			{ChangeCapital, was.Capital, now.Capital},
+			{ChangeFrom, was.From, now.From},
+			{ChangeTo, was.To, now.To},
+		}
+		for _, field := range fields {
+			if sameSubmittedValue(field.was, field.now) {
+				continue
+			}
+
+			changes = append(changes, SnapshotChange{
+				LineId:    was.LineId,
+				KingdomId: was.KingdomId,
+				Field:     field.name,
+				Submitted: field.was,
+				Current:   field.now,
+			})
+		}
+	}
+
+	// lines left over did not exist at submission
+	for _, kingdom := range current.Kingdoms {
+		if _, added := currentLines[kingdom.LineId]; added {
+			changes = append(changes, SnapshotChange{
+				LineId:    kingdom.LineId,
+				KingdomId: kingdom.KingdomId,
+				Field:     ChangeAdded,
+				Current:   kingdom,
+			})
+		}
+	}
+
+	return changes
+}
+
+// GetApplicationSnapshot returns the latest submission snapshot of the
+// application together with what has changed since in live data.
+func (r *Repository) GetApplicationSnapshot(user schema.User, applicationId string) (ApplicationSnapshotView, error) {
+	var tx *gorm.DB = r.db
+
+	var app schema.RulerApplication
+	err := tx.Where("id = ?", applicationId).First(&app).Error
+	if err != nil {
+		return ApplicationSnapshotView{}, err
+	}
+
+	err = loadCoAuthors(tx, &app)
+	if err != nil {
+		return ApplicationSnapshotView{}, err
+	}
+
+	err = policy.Authorize(user, policy.ReadApplication, &app)
+	if err != nil {
+		return ApplicationSnapshotView{}, err
+	}
+
+	return latestSnapshotView(tx, app)
+}
+
+// latestSnapshotView compares the latest submission snapshot of app with its
+// live data.
+func latestSnapshotView(tx *gorm.DB, app schema.RulerApplication) (ApplicationSnapshotView, error) {
+	var snapshot schema.ApplicationSnapshot
+	err := tx.Where("application_refer = ?", app.Id).
+		Order("date_create DESC, id DESC").
+		First(&snapshot).Error
+	if err != nil {
+		return ApplicationSnapshotView{}, err
+	}
+
+	var submitted SubmittedApplication
+	err = json.Unmarshal(snapshot.Data, &submitted)
+	if err != nil {
+		return ApplicationSnapshotView{}, err
+	}
+
+	current, err := submittedApplication(tx, app)
+	if err != nil {
+		return ApplicationSnapshotView{}, err
+	}
+
+	changes := diffSubmission(submitted, current)
+
+	return ApplicationSnapshotView{
+		ApplicationId: app.Id,
+		DateCreate:    snapshot.DateCreate,
+		Snapshot:      submitted,
+		Changed:       len(changes) > 0,
+		Changes:       changes,
+	}, nil
+}
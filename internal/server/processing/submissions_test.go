@@ -0,0 +1,53 @@
+package processing
+
+import (
+	"strconv"
+	"testing"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	role "kingdoms/internal/server/app/userRole"
+)
+
+func TestModeratorSeesSubmission(t *testing.T) {
+	r := testRepository(t)
+	owner := testUser(t, r, "owner", role.Buyer)
+	manager := testUser(t, r, "manager", role.Manager)
+	kiev := testKingdom(t, r, "Киевское", 100)
+	app := testApplication(t, r, owner, appState.Submitted, kiev)
+
+	err := takeSubmissionSnapshot(r.db, app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.db.Model(&schema.Kingdom{}).Where("id = ?", kiev.Id).Update("name", "Великое Киевское").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := strconv.FormatUint(uint64(app.Id), 10)
+
+	moderated, err := r.GetApplicationWithKingdoms(manager, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moderated.Submission == nil {
+		t.Fatal("moderator view has no submission")
+	}
+	if got := moderated.Submission.Snapshot.Kingdoms[0].Name; got != "Киевское" {
+		t.Errorf("submitted name = %q, want the name at submission", got)
+	}
+	if !moderated.Submission.Changed || len(moderated.Submission.Changes) != 1 ||
+		moderated.Submission.Changes[0].Field != ChangeName {
+		t.Errorf("changes = %+v, want the rename", moderated.Submission.Changes)
+	}
+
+	owned, err := r.GetApplicationWithKingdoms(owner, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owned.Submission != nil {
+		t.Errorf("owner view has submission %+v, want none", owned.Submission)
+	}
+}
@@ -0,0 +1,200 @@
+package processing
+
+import (
+	"errors"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/locale"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TranslateKingdoms replaces Name, Capital and Description of every kingdom
+// with its translation to loc. Fields with no translation keep the source text.
+func (r *Repository) TranslateKingdoms(kingdoms []schema.Kingdom, loc locale.Locale) error {
+	if loc == locale.Default || len(kingdoms) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(kingdoms))
+	for _, kingdom := range kingdoms {
+		ids = append(ids, kingdom.Id)
+	}
+
+	byKingdom, err := kingdomTranslations(r.db.Where("kingdom_refer IN ?", ids), loc)
+	if err != nil {
+		return err
+	}
+
+	for i := range kingdoms {
+		applyTranslation(&kingdoms[i], byKingdom)
+	}
+
+	return nil
+}
+
+// kingdomTranslations loads the translations to loc matched by tx, keyed by
+// kingdom id.
+func kingdomTranslations(tx *gorm.DB, loc locale.Locale) (map[int]schema.KingdomTranslation, error) {
+	var translations []schema.KingdomTranslation
+
+	err := tx.Where("locale = ?", string(loc)).Find(&translations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byKingdom := make(map[int]schema.KingdomTranslation, len(translations))
+	for _, translation := range translations {
+		byKingdom[translation.KingdomRefer] = translation
+	}
+
+	return byKingdom, nil
+}
+
+func applyTranslation(kingdom *schema.Kingdom, byKingdom map[int]schema.KingdomTranslation) {
+	translation, ok := byKingdom[int(kingdom.Id)]
+	if !ok {
+		return
+	}
+
+	if translation.Name != "" {
+		kingdom.Name = translation.Name
+	}
+	if translation.Capital != "" {
+		kingdom.Capital = translation.Capital
+	}
+	if translation.Description != "" {
+		kingdom.Description = translation.Description
+	}
+}
+
+func (r *Repository) GetKingdomTranslations(kingdomId uint) ([]schema.KingdomTranslation, error) {
+	var translationsToReturn []schema.KingdomTranslation
+
+	var tx *gorm.DB = r.db
+
+	err := tx.Where("kingdom_refer = ?", kingdomId).
+		Order("locale").
+		Find(&translationsToReturn).Error
+	if err != nil {
+		return []schema.KingdomTranslation{}, err
+	}
+
+	return translationsToReturn, nil
+}
+
+func (r *Repository) UpdateKingdomTranslation(translationToUpdate KingdomTranslationToUpdate) error {
+	loc, ok := locale.Parse(translationToUpdate.Locale)
+	if !ok {
+		return errors.New("unsupported locale: " + translationToUpdate.Locale)
+	}
+	if loc == locale.Default {
+		return errors.New("source locale is edited through the kingdom itself")
+	}
+
+	var tx *gorm.DB = r.db
+
+	var kingdom schema.Kingdom
+	err := tx.Select("id").Where("id = ?", translationToUpdate.KingdomId).First(&kingdom).Error
+	if err != nil {
+		return err
+	}
+
+	translation := schema.KingdomTranslation{
+		KingdomRefer: int(translationToUpdate.KingdomId),
+		Locale:       string(loc),
+		Name:         translationToUpdate.Name,
+		Capital:      translationToUpdate.Capital,
+		Description:  translationToUpdate.Description,
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kingdom_refer"}, {Name: "locale"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "capital", "description"}),
+	}).Create(&translation).Error
+}
+
+func (r *Repository) DeleteKingdomTranslation(translationToDelete KingdomTranslationToUpdate) error {
+	loc, ok := locale.Parse(translationToDelete.Locale)
+	if !ok {
+		return errors.New("unsupported locale: " + translationToDelete.Locale)
+	}
+
+	var tx *gorm.DB = r.db
+
+	res := tx.Where("kingdom_refer = ? AND locale = ?", translationToDelete.KingdomId, string(loc)).
+		Delete(&schema.KingdomTranslation{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("no necessary translation found")
+	}
+
+	return nil
+}
+
+// GetMissingTranslations reports, for every kingdom and every non-source
+// locale, which of the translatable fields are still untranslated.
+func (r *Repository) GetMissingTranslations() ([]MissingTranslation, error) {
+	var kingdoms []schema.Kingdom
+
+	var tx *gorm.DB = r.db
+
+	err := tx.Select("id, name, capital, description").
+		Order("id").
+		Find(&kingdoms).Error
+	if err != nil {
+		return []MissingTranslation{}, err
+	}
+
+	var translations []schema.KingdomTranslation
+	err = tx.Find(&translations).Error
+	if err != nil {
+		return []MissingTranslation{}, err
+	}
+
+	type key struct {
+		kingdom int
+		locale  string
+	}
+
+	byKey := make(map[key]schema.KingdomTranslation, len(translations))
+	for _, translation := range translations {
+		byKey[key{translation.KingdomRefer, translation.Locale}] = translation
+	}
+
+	missingToReturn := []MissingTranslation{}
+	for _, kingdom := range kingdoms {
+		for _, loc := range locale.Supported {
+			if loc == locale.Default {
+				continue
+			}
+
+			translation := byKey[key{int(kingdom.Id), string(loc)}]
+
+			var fields []string
+			if translation.Name == "" && kingdom.Name != "" {
+				fields = append(fields, "Name")
+			}
+			if translation.Capital == "" && kingdom.Capital != "" {
+				fields = append(fields, "Capital")
+			}
+			if translation.Description == "" && kingdom.Description != "" {
+				fields = append(fields, "Description")
+			}
+
+			if len(fields) != 0 {
+				missingToReturn = append(missingToReturn, MissingTranslation{
+					KingdomId:   kingdom.Id,
+					KingdomName: kingdom.Name,
+					Locale:      string(loc),
+					Fields:      fields,
+				})
+			}
+		}
+	}
+
+	return missingToReturn, nil
+}
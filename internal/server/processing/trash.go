@@ -0,0 +1,182 @@
+package processing
+
+import (
+	"errors"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/app/policy"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotInTrash          = errors.New("application is not in the trash")
+	ErrRestoreWindowClosed = errors.New("application can no longer be restored")
+	ErrRestoreFromTrash    = errors.New("deleted applications leave the trash only by being restored")
+)
+
+// trashUpdates adds to updates what moving app to the trash records: the
+// state it was deleted from and when.
+func trashUpdates(app schema.RulerApplication, updates map[string]interface{}) {
+	updates["previous_state"] = app.State
+	updates["date_delete"] = time.Now().UTC()
+}
+
+// GetTrash returns the caller's deleted applications, most recent first.
+func (r *Repository) GetTrash(user schema.User) ([]schema.RulerApplication, error) {
+	var tx *gorm.DB = r.db
+
+	applicationsToReturn := []schema.RulerApplication{}
+	err := tx.Where("creator_refer = ? AND state = ?", user.Id, appState.Deleted).
+		Order("date_delete DESC, id").
+		Find(&applicationsToReturn).Error
+	if err != nil {
+		return []schema.RulerApplication{}, err
+	}
+
+	return applicationsToReturn, nil
+}
+
+// RestoreApplication takes an application out of the trash back to draft,
+// whatever state it was deleted from: what was submitted goes through
+// moderation again. PreviousState is kept, so callers can tell the owner when
+// it was not a draft. It is only restored if its creator has no other draft.
+func (r *Repository) RestoreApplication(user schema.User, applicationId string) (schema.RulerApplication, error) {
+	var restored schema.RulerApplication
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		app, err := lockedApplication(tx, applicationId)
+		if err != nil {
+			return err
+		}
+
+		err = policy.Authorize(user, policy.RestoreApplication, &app)
+		if err != nil {
+			return err
+		}
+
+		if app.State != appState.Deleted || app.DateDelete == nil {
+			return ErrNotInTrash
+		}
+
+		transition, err := appState.Check(app.State, appState.Draft, user.Role,
+			policy.Relations(user, &app)&policy.Owner != 0)
+		if err != nil {
+			return err
+		}
+
+		if time.Since(*app.DateDelete) > r.cfg.Trash.RestoreWindow {
+			return ErrRestoreWindowClosed
+		}
+
+		_, err = userDraft(tx, schema.User{Id: uint(app.CreatorRefer)}, false)
+		if err == nil {
+			return ErrDraftExists
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		err = applyTransition(tx, app, map[string]interface{}{
+			"state":       transition.To,
+			"date_delete": nil,
+			// a restored draft starts its expiry period over
+			"last_modified": time.Now().UTC(),
+			"date_warned":   nil,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = recordEvent(tx, &schema.ApplicationEvent{
+			ApplicationRefer: int(app.Id),
+			ActorRefer:       int(user.Id),
+			Type:             EventStateChange,
+		}, app.State, transition.To)
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("id = ?", app.Id).First(&restored).Error
+	})
+	if err != nil {
+		return schema.RulerApplication{}, err
+	}
+
+	return restored, nil
+}
+
+// PurgeTrash deletes for good the applications deleted longer than the
+// retention ago, logging each one to the purge log.
+func (r *Repository) PurgeTrash() ([]schema.PurgeLog, error) {
+	purged := []schema.PurgeLog{}
+	cutoff := time.Now().UTC().Add(-r.cfg.Trash.Retention)
+	var storageKeys []string
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var expired []schema.RulerApplication
+		err := forUpdate(tx).
+			Where("state = ? AND date_delete < ?", appState.Deleted, cutoff).
+			Order("id").
+			Find(&expired).Error
+		if err != nil {
+			return err
+		}
+
+		for _, app := range expired {
+			entry := schema.PurgeLog{
+				ApplicationRefer: int(app.Id),
+				CreatorRefer:     app.CreatorRefer,
+				Ruler:            app.Ruler,
+				PreviousState:    app.PreviousState,
+				DateDelete:       *app.DateDelete,
+			}
+
+			err = tx.Create(&entry).Error
+			if err != nil {
+				return err
+			}
+
+			var keys []string
+			err = tx.Model(&schema.Attachment{}).
+				Where("application_refer = ?", app.Id).
+				Pluck("storage_key", &keys).Error
+			if err != nil {
+				return err
+			}
+			storageKeys = append(storageKeys, keys...)
+
+			var charterKeys []string
+			err = tx.Model(&schema.Charter{}).
+				Where("application_refer = ? AND storage_key != ''", app.Id).
+				Pluck("storage_key", &charterKeys).Error
+			if err != nil {
+				return err
+			}
+			storageKeys = append(storageKeys, charterKeys...)
+
+			// lines, history, comments, snapshots, attachments and charters go with it by cascade
+			err = tx.Where("id = ?", app.Id).Delete(&schema.RulerApplication{}).Error
+			if err != nil {
+				return err
+			}
+
+			purged = append(purged, entry)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return []schema.PurgeLog{}, err
+	}
+
+	// files are removed once the rows are gone for good; one left behind is
+	// only wasted space
+	for _, key := range storageKeys {
+		_ = r.files.Delete(key)
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,132 @@
+package processing
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/appState"
+	"kingdoms/internal/server/app/policy"
+	role "kingdoms/internal/server/app/userRole"
+)
+
+// trashed moves app to the trash as if it was deleted from previous ago.
+func trashed(t *testing.T, r *Repository, app schema.RulerApplication, previous appState.State,
+	ago time.Duration) string {
+	t.Helper()
+
+	err := r.db.Model(&schema.RulerApplication{}).Where("id = ?", app.Id).Updates(map[string]interface{}{
+		"state":          appState.Deleted,
+		"previous_state": previous,
+		"date_delete":    time.Now().UTC().Add(-ago),
+	}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strconv.FormatUint(uint64(app.Id), 10)
+}
+
+func TestRestoreApplication(t *testing.T) {
+	r := testRepository(t)
+	r.cfg.Trash.RestoreWindow = 24 * time.Hour
+
+	owner := testUser(t, r, "owner", role.Buyer)
+	manager := testUser(t, r, "manager", role.Manager)
+	admin := testUser(t, r, "admin", role.Admin)
+
+	t.Run("owner restores a draft", func(t *testing.T) {
+		id := trashed(t, r, testApplication(t, r, owner, appState.Draft), appState.Draft, time.Hour)
+
+		restored, err := r.RestoreApplication(owner, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.State != appState.Draft || restored.DateDelete != nil || restored.PreviousState != appState.Draft {
+			t.Errorf("restored = %s, deleted %v, previous %s, want a draft that was a draft",
+				restored.State, restored.DateDelete, restored.PreviousState)
+		}
+
+		var events int64
+		err = r.db.Model(&schema.ApplicationEvent{}).
+			Where("application_refer = ? AND type = ?", restored.Id, EventStateChange).
+			Count(&events).Error
+		if err != nil || events != 1 {
+			t.Errorf("%d state change events (%v), want one", events, err)
+		}
+
+		// out of the way of the restores below, which need no other draft
+		err = r.db.Model(&schema.RulerApplication{}).Where("id = ?", restored.Id).Update("state", appState.Submitted).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("submitted comes back as a draft", func(t *testing.T) {
+		app := testApplication(t, r, owner, appState.Submitted)
+		id := trashed(t, r, app, appState.Submitted, time.Hour)
+
+		draft := testApplication(t, r, owner, appState.Draft)
+		_, err := r.RestoreApplication(owner, id)
+		if !errors.Is(err, ErrDraftExists) {
+			t.Fatalf("restoring next to a draft: error = %v, want ErrDraftExists", err)
+		}
+		trashed(t, r, draft, appState.Draft, time.Hour)
+
+		restored, err := r.RestoreApplication(admin, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.State != appState.Draft || restored.PreviousState != appState.Submitted {
+			t.Errorf("restored = %s, previous %s, want a draft that was submitted", restored.State, restored.PreviousState)
+		}
+	})
+
+	t.Run("only the owner and admins restore", func(t *testing.T) {
+		id := trashed(t, r, testApplication(t, r, admin, appState.Draft), appState.Draft, time.Hour)
+
+		_, err := r.RestoreApplication(manager, id)
+		if !errors.Is(err, policy.ErrForbidden) {
+			t.Errorf("manager restores: error = %v, want ErrForbidden", err)
+		}
+		_, err = r.RestoreApplication(owner, id)
+		if !errors.Is(err, policy.ErrForbidden) {
+			t.Errorf("stranger restores: error = %v, want ErrForbidden", err)
+		}
+	})
+
+	t.Run("restore window", func(t *testing.T) {
+		other := testUser(t, r, "other", role.Buyer)
+		id := trashed(t, r, testApplication(t, r, other, appState.Draft), appState.Draft, 48*time.Hour)
+
+		_, err := r.RestoreApplication(other, id)
+		if !errors.Is(err, ErrRestoreWindowClosed) {
+			t.Errorf("restoring after the window: error = %v, want ErrRestoreWindowClosed", err)
+		}
+	})
+
+	t.Run("status update does not restore", func(t *testing.T) {
+		other := testUser(t, r, "another", role.Buyer)
+		app := testApplication(t, r, other, appState.Draft)
+		trashed(t, r, app, appState.Draft, time.Hour)
+
+		_, err := r.UpdateApplicationStatusUser(other, ApplicationToUpdate{Id: app.Id, State: string(appState.Draft)})
+		if !errors.Is(err, ErrRestoreFromTrash) {
+			t.Errorf("status update to draft: error = %v, want ErrRestoreFromTrash", err)
+		}
+		if state := applicationState(t, r, app.Id); state != appState.Deleted {
+			t.Errorf("application is %s, want it still deleted", state)
+		}
+	})
+
+	t.Run("not in the trash", func(t *testing.T) {
+		app := testApplication(t, r, admin, appState.Submitted)
+
+		_, err := r.RestoreApplication(admin, strconv.FormatUint(uint64(app.Id), 10))
+		if !errors.Is(err, ErrNotInTrash) {
+			t.Errorf("restoring a submitted application: error = %v, want ErrNotInTrash", err)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const applicationLockPrefix = "lock.application."
+
+// lockScript takes a free lock or extends one the caller already holds, in one
+// step, so the lock cannot expire or change hands between the check and the
+// extension. It returns 1 for a lock taken, 2 for one extended and 0 when
+// someone else holds it.
+var lockScript = redis.NewScript(`
+local holder = redis.call("GET", KEYS[1])
+if not holder then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+if holder == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 2
+end
+return 0
+`)
+
+// unlockScript deletes a lock only while it is still held by the caller, so an
+// expired lock taken over by another moderator is never released by mistake.
+// It returns whoever else holds the lock, or an empty string.
+var unlockScript = redis.NewScript(`
+local holder = redis.call("GET", KEYS[1])
+if holder == ARGV[1] then
+	redis.call("DEL", KEYS[1])
+	return ""
+end
+return holder or ""
+`)
+
+type ApplicationLock struct {
+	ApplicationId uint
+	Moderator     string
+	ExpiresAt     time.Time
+}
+
+func getApplicationLockKey(applicationId uint) string {
+	return servicePrefix + applicationLockPrefix + strconv.FormatUint(uint64(applicationId), 10)
+}
+
+// LockApplication takes the lock on an application for moderator. A lock the
+// moderator already holds is extended; a lock held by someone else is left alone.
+// taken tells a lock that was free from one the moderator held before.
+func (c *Client) LockApplication(ctx context.Context, applicationId uint, moderator string,
+	ttl time.Duration) (locked bool, taken bool, err error) {
+	result, err := lockScript.Run(ctx, c.client, []string{getApplicationLockKey(applicationId)},
+		moderator, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, false, err
+	}
+
+	return result != 0, result == 1, nil
+}
+
+// UnlockApplication releases the lock if moderator still holds it. It returns
+// the other moderator holding the lock, or an empty string when the lock was
+// released or the application was free.
+func (c *Client) UnlockApplication(ctx context.Context, applicationId uint, moderator string) (string, error) {
+	return unlockScript.Run(ctx, c.client, []string{getApplicationLockKey(applicationId)}, moderator).Text()
+}
+
+// ForceUnlockApplication releases the lock whoever holds it.
+func (c *Client) ForceUnlockApplication(ctx context.Context, applicationId uint) error {
+	return c.client.Del(ctx, getApplicationLockKey(applicationId)).Err()
+}
+
+// ApplicationLockHolder returns the moderator holding the lock, or an empty
+// string when the application is free.
+func (c *Client) ApplicationLockHolder(ctx context.Context, applicationId uint) (string, error) {
+	holder, err := c.client.Get(ctx, getApplicationLockKey(applicationId)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+
+	return holder, err
+}
+
+func (c *Client) ApplicationLocks(ctx context.Context) ([]ApplicationLock, error) {
+	locks := []ApplicationLock{}
+	prefix := servicePrefix + applicationLockPrefix
+
+	iter := c.client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+
+		applicationId, err := strconv.ParseUint(strings.TrimPrefix(key, prefix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		holder, err := c.client.Get(ctx, key).Result()
+		if err == redis.Nil {
+			// expired between SCAN and GET
+			continue
+		}
+		if err != nil {
+			return []ApplicationLock{}, err
+		}
+
+		ttl, err := c.client.PTTL(ctx, key).Result()
+		if err != nil {
+			return []ApplicationLock{}, err
+		}
+
+		locks = append(locks, ApplicationLock{
+			ApplicationId: uint(applicationId),
+			Moderator:     holder,
+			ExpiresAt:     time.Now().Add(ttl),
+		})
+	}
+
+	if err := iter.Err(); err != nil {
+		return []ApplicationLock{}, err
+	}
+
+	return locks, nil
+}
@@ -0,0 +1,141 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const manifestName = "manifest.json"
+
+type ManifestFile struct {
+	Path   string
+	Size   int
+	SHA256 string
+}
+
+type Manifest struct {
+	GeneratedAt time.Time
+	PageSize    int
+	Locales     []string
+	Kingdoms    int
+	Files       []ManifestFile
+}
+
+// fileSet writes files under root and skips the ones whose content hash has
+// not changed since the previous snapshot.
+type fileSet struct {
+	root     string
+	previous map[string]ManifestFile
+	files    map[string]ManifestFile
+	written  int
+}
+
+func newFileSet(root string) (*fileSet, error) {
+	set := &fileSet{
+		root:     root,
+		previous: map[string]ManifestFile{},
+		files:    map[string]ManifestFile{},
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, manifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return set, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var previous Manifest
+	err = json.Unmarshal(data, &previous)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range previous.Files {
+		set.previous[file.Path] = file
+	}
+
+	return set, nil
+}
+
+func (s *fileSet) writeJSON(path string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return s.write(path, data)
+}
+
+func (s *fileSet) write(path string, data []byte) error {
+	sum := sha256.Sum256(data)
+	file := ManifestFile{
+		Path:   filepath.ToSlash(path),
+		Size:   len(data),
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+
+	if _, exists := s.files[file.Path]; exists {
+		return errors.New("file written twice: " + file.Path)
+	}
+	s.files[file.Path] = file
+
+	fullPath := filepath.Join(s.root, path)
+	if s.previous[file.Path] == file {
+		if _, err := os.Stat(fullPath); err == nil {
+			return nil
+		}
+	}
+
+	err := os.MkdirAll(filepath.Dir(fullPath), 0o755)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := fullPath + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0o644)
+	if err != nil {
+		return err
+	}
+
+	s.written++
+
+	return os.Rename(tmpPath, fullPath)
+}
+
+// finish removes files left from the previous snapshot and writes the manifest.
+func (s *fileSet) finish(manifest Manifest) (int, error) {
+	removed := 0
+	for path := range s.previous {
+		if _, exists := s.files[path]; exists {
+			continue
+		}
+
+		err := os.Remove(filepath.Join(s.root, filepath.FromSlash(path)))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+		removed++
+	}
+
+	manifest.Files = make([]ManifestFile, 0, len(s.files))
+	for _, file := range s.files {
+		manifest.Files = append(manifest.Files, file)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return removed, err
+	}
+
+	return removed, os.WriteFile(filepath.Join(s.root, manifestName), data, 0o644)
+}
@@ -0,0 +1,200 @@
+package snapshot
+
+import (
+	"encoding/base64"
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"kingdoms/internal/database/schema"
+	"kingdoms/internal/server/app/locale"
+	"kingdoms/internal/server/models/responseModels"
+	"kingdoms/internal/server/processing"
+)
+
+type Options struct {
+	Out      string
+	PageSize int
+	Locales  []locale.Locale
+}
+
+type Result struct {
+	Files   int
+	Written int
+	Removed int
+}
+
+var imageExtensions = map[string]string{
+	"image/png":     "png",
+	"image/jpeg":    "jpg",
+	"image/gif":     "gif",
+	"image/webp":    "webp",
+	"image/svg+xml": "svg",
+}
+
+// Generate writes the public read API into opts.Out as static JSON files laid
+// out like the REST paths: kingdoms/index.json and kingdoms/page/N.json for the
+// feed, kingdom/ID.json and kingdoms/SLUG.json for a single kingdom. The
+// default locale lives at the root and every other locale under its own
+// directory. Images are written once to img/kingdoms and referenced by path.
+func Generate(repo *processing.Repository, opts Options) (Result, error) {
+	if opts.PageSize <= 0 {
+		return Result{}, errors.New("page size must be positive")
+	}
+
+	files, err := newFileSet(opts.Out)
+	if err != nil {
+		return Result{}, err
+	}
+
+	images := map[uint]string{}
+	err = repo.ExportKingdoms("", true, locale.Default, func(kingdom schema.Kingdom) error {
+		imagePath, data, err := decodeImage(kingdom)
+		if err != nil || imagePath == "" {
+			return err
+		}
+
+		images[kingdom.Id] = imagePath
+
+		return files.write(imagePath, data)
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	manifest := Manifest{
+		GeneratedAt: time.Now().UTC(),
+		PageSize:    opts.PageSize,
+	}
+
+	for _, loc := range opts.Locales {
+		var kingdoms []schema.Kingdom
+		err = repo.ExportKingdoms("", false, loc, func(kingdom schema.Kingdom) error {
+			kingdom.Image = images[kingdom.Id]
+			kingdoms = append(kingdoms, kingdom)
+
+			return nil
+		})
+		if err != nil {
+			return Result{}, err
+		}
+
+		prefix := ""
+		if loc != locale.Default {
+			prefix = string(loc)
+		}
+
+		err = writeKingdoms(files, prefix, kingdoms, opts.PageSize)
+		if err != nil {
+			return Result{}, err
+		}
+
+		manifest.Locales = append(manifest.Locales, string(loc))
+		manifest.Kingdoms = len(kingdoms)
+	}
+
+	removed, err := files.finish(manifest)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Files:   len(files.files),
+		Written: files.written,
+		Removed: removed,
+	}, nil
+}
+
+func writeKingdoms(files *fileSet, prefix string, kingdoms []schema.Kingdom, pageSize int) error {
+	pages := (len(kingdoms) + pageSize - 1) / pageSize
+	if pages == 0 {
+		pages = 1
+	}
+
+	for page := 1; page <= pages; page++ {
+		from := (page - 1) * pageSize
+		to := from + pageSize
+		if to > len(kingdoms) {
+			to = len(kingdoms)
+		}
+
+		response := responseModels.ResponseDefault{
+			Code:    200,
+			Status:  "ok",
+			Message: "kingdoms found",
+			Body: map[string]interface{}{
+				"Kingdoms":          kingdoms[from:to],
+				"Draft_Application": 0,
+				"Page":              page,
+				"Pages":             pages,
+			},
+		}
+
+		err := files.writeJSON(path.Join(prefix, "kingdoms", "page", strconv.Itoa(page)+".json"), response)
+		if err != nil {
+			return err
+		}
+
+		if page == 1 {
+			err = files.writeJSON(path.Join(prefix, "kingdoms", "index.json"), response)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, kingdom := range kingdoms {
+		response := responseModels.ResponseDefault{
+			Code:    200,
+			Status:  "ok",
+			Message: "kingdom found",
+			Body:    kingdom,
+		}
+
+		err := files.writeJSON(path.Join(prefix, "kingdom", strconv.Itoa(int(kingdom.Id))+".json"), response)
+		if err != nil {
+			return err
+		}
+
+		if kingdom.Slug != "" {
+			err = files.writeJSON(path.Join(prefix, "kingdoms", kingdom.Slug+".json"), response)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// decodeImage turns the data URI stored in schema.Kingdom into a file path and
+// its content. Kingdoms without an image get an empty path.
+func decodeImage(kingdom schema.Kingdom) (string, []byte, error) {
+	if !strings.HasPrefix(kingdom.Image, "data:") {
+		return "", nil, nil
+	}
+
+	header, encoded, found := strings.Cut(kingdom.Image[len("data:"):], ",")
+	if !found || !strings.HasSuffix(header, ";base64") {
+		return "", nil, errors.New("kingdom " + strconv.Itoa(int(kingdom.Id)) + ": image is not a base64 data URI")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, err
+	}
+
+	extension, ok := imageExtensions[strings.TrimSuffix(header, ";base64")]
+	if !ok {
+		extension = "bin"
+	}
+
+	name := kingdom.Slug
+	if name == "" {
+		name = strconv.Itoa(int(kingdom.Id))
+	}
+
+	return path.Join("img", "kingdoms", name+"."+extension), data, nil
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalPath(t *testing.T) {
+	root := filepath.Join("srv", "attachments")
+	local := &Local{root: root}
+
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "applications/12/abc", want: filepath.Join(root, "applications", "12", "abc")},
+		{key: "/applications/12/abc", want: filepath.Join(root, "applications", "12", "abc")},
+		{key: "applications//12/./abc", want: filepath.Join(root, "applications", "12", "abc")},
+		{key: "charters/3/code.pdf", want: filepath.Join(root, "charters", "3", "code.pdf")},
+		{key: "", wantErr: true},
+		{key: "/", wantErr: true},
+		{key: ".", wantErr: true},
+		{key: "..", wantErr: true},
+		{key: "../etc/passwd", wantErr: true},
+		{key: "applications/../../etc/passwd", wantErr: true},
+		{key: "applications/12/..", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := local.path(tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("path(%q) error = %v, want error %v", tt.key, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("path(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	local, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const key = "applications/1/file"
+
+	_, err = local.Open(key)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open of a missing key: error = %v, want ErrNotFound", err)
+	}
+
+	err = local.Put(key, strings.NewReader("грамота"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := local.Open(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(content)
+	content.Close()
+	if err != nil || string(data) != "грамота" {
+		t.Fatalf("Open() = %q, %v, want the content put", data, err)
+	}
+
+	err = local.Delete(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = local.Delete(key); err != nil {
+		t.Errorf("deleting a missing file: error = %v, want none", err)
+	}
+
+	_, err = local.Open(key)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestNewLocalNeedsDirectory(t *testing.T) {
+	if _, err := NewLocal(""); err == nil {
+		t.Error("NewLocal(\"\") succeeded")
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	var files Storage = Disabled{}
+
+	if err := files.Put("key", strings.NewReader("x")); !errors.Is(err, ErrDisabled) {
+		t.Errorf("Put error = %v, want ErrDisabled", err)
+	}
+	if _, err := files.Open("key"); !errors.Is(err, ErrDisabled) {
+		t.Errorf("Open error = %v, want ErrDisabled", err)
+	}
+	if err := files.Delete("key"); !errors.Is(err, ErrDisabled) {
+		t.Errorf("Delete error = %v, want ErrDisabled", err)
+	}
+}
@@ -0,0 +1,32 @@
+// Package storage keeps uploaded files outside the database.
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	// ErrNotFound is returned when no file is stored under the key.
+	ErrNotFound = errors.New("file not found")
+	// ErrDisabled is returned by Disabled for every file.
+	ErrDisabled = errors.New("file storage is not configured")
+)
+
+// Storage is a place files can be put into and read back by key. Keys are
+// slash separated paths chosen by the caller, e.g. applications/12/<uuid>.
+type Storage interface {
+	Put(key string, content io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// Disabled is the storage of tools that run without one configured and
+// never touch files, e.g. the snapshot command.
+type Disabled struct{}
+
+func (Disabled) Put(string, io.Reader) error { return ErrDisabled }
+
+func (Disabled) Open(string) (io.ReadCloser, error) { return nil, ErrDisabled }
+
+func (Disabled) Delete(string) error { return ErrDisabled }